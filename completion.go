@@ -0,0 +1,467 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// completion.go: generate shell completion scripts from a docopt usage.
+//
+package main
+
+import (
+	"fmt"
+	"github.com/docopt/docopts/pkg/grammar"
+	"github.com/docopt/docopts/pkg/shellout"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Upper bound of positional sequences expanded from usage patterns.
+const max_sequences = 512
+
+// Completion data extracted from a parsed usage.
+type Completion struct {
+	// command name to complete
+	Name string
+	// all options names, short and long
+	Options []string
+	// options names which take an argument
+	Options_with_arg []string
+	// option name => description
+	Descriptions map[string]string
+	// positional sequences, one per usage alternative. Commands are kept
+	// verbatim, arguments are <placeholder> followed by ... if repeatable.
+	Sequences []string
+}
+
+func New_completion(doc *grammar.Doc, name string) *Completion {
+	c := &Completion{
+		Name:         name,
+		Descriptions: make(map[string]string),
+	}
+	if c.Name == "" {
+		c.Name = doc.Prog
+	}
+
+	seen := make(map[string]bool)
+	for _, o := range doc.Options {
+		for _, n := range o.Names() {
+			// an option described twice
+			if seen[n] {
+				continue
+			}
+			seen[n] = true
+			c.Options = append(c.Options, n)
+			c.Descriptions[n] = o.Description
+			if o.Argcount > 0 {
+				c.Options_with_arg = append(c.Options_with_arg, n)
+			}
+		}
+	}
+
+	seen = make(map[string]bool)
+	for _, seq := range positional_sequences(doc.Pattern) {
+		s := strings.Join(seq, " ")
+		if !seen[s] {
+			seen[s] = true
+			c.Sequences = append(c.Sequences, s)
+		}
+	}
+	sort.Strings(c.Sequences)
+
+	return c
+}
+
+// Expand a pattern into all its possible sequences of positional elements.
+// Options are ignored.
+func positional_sequences(p *grammar.Pattern) [][]string {
+	switch p.Type {
+	case grammar.Command:
+		return [][]string{{p.Name}}
+	case grammar.Argument:
+		name := p.Name
+		if !strings.HasPrefix(name, "<") {
+			name = "<" + name + ">"
+		}
+		return [][]string{{name}}
+	case grammar.Option_leaf, grammar.Options_shortcut:
+		return [][]string{{}}
+	case grammar.Either:
+		result := [][]string{}
+		for _, c := range p.Children {
+			result = append(result, positional_sequences(c)...)
+		}
+		return limit_sequences(result)
+	case grammar.One_or_more:
+		result := sequences_product(p.Children, false)
+		for i, seq := range result {
+			// a single repeatable argument is marked, other repetition are
+			// expanded only once
+			if len(seq) == 1 && strings.HasPrefix(seq[0], "<") && !strings.HasSuffix(seq[0], "...") {
+				result[i] = []string{seq[0] + "..."}
+			}
+		}
+		return result
+	case grammar.Optional:
+		return sequences_product(p.Children, true)
+	}
+	// Required
+	return sequences_product(p.Children, false)
+}
+
+// cartesian product of children sequences, each child may be skipped if optional.
+func sequences_product(children []*grammar.Pattern, optional bool) [][]string {
+	result := [][]string{{}}
+	for _, c := range children {
+		child_seqs := positional_sequences(c)
+		if optional {
+			child_seqs = append(child_seqs, []string{})
+		}
+		product := [][]string{}
+		for _, prefix := range result {
+			for _, seq := range child_seqs {
+				s := make([]string, 0, len(prefix)+len(seq))
+				s = append(s, prefix...)
+				s = append(s, seq...)
+				product = append(product, s)
+			}
+		}
+		result = limit_sequences(product)
+	}
+	return result
+}
+
+func limit_sequences(seqs [][]string) [][]string {
+	if len(seqs) > max_sequences {
+		return seqs[:max_sequences]
+	}
+	return seqs
+}
+
+// shell function identifier derived from the command name
+func (c *Completion) Func_name() string {
+	re := regexp.MustCompile(`[^A-Za-z0-9_]`)
+	return "_" + re.ReplaceAllString(c.Name, "_") + "_docopts_complete"
+}
+
+func bash_words(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = "'" + shellout.Shellquote(w) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shell code matching typed positional words against c.Sequences, shared by
+// bash and zsh. Outputs next candidates, without duplicates, in $candidates
+// and $files.
+const sh_match_sequences = `    for seq in "${sequences[@]}" ; do
+        elems=( $seq )
+        j=0
+        ok=true
+        for w in "${positional[@]}" ; do
+            if (( j >= ${#elems[@]} )) ; then
+                ok=false
+                break
+            fi
+            elem=${elems[j]}
+            case $elem in
+                '<'*'>...')
+                    # repeatable argument, may be followed by a command
+                    if (( j + 1 < ${#elems[@]} )) && [[ $w == "${elems[j+1]}" ]] ; then
+                        j=$((j + 2))
+                    fi
+                    ;;
+                '<'*'>')
+                    j=$((j + 1))
+                    ;;
+                *)
+                    if [[ $w == "$elem" ]] ; then
+                        j=$((j + 1))
+                    else
+                        ok=false
+                        break
+                    fi
+                    ;;
+            esac
+        done
+        $ok || continue
+        (( j < ${#elems[@]} )) || continue
+        for elem in "${elems[@]:j:2}" ; do
+            case $elem in
+                '<'*) files=true ;;
+                *)
+                    # many sequences can give the same command
+                    [[ " ${candidates[*]} " == *" $elem "* ]] || candidates+=( "$elem" )
+                    ;;
+            esac
+            # only a repeatable argument gives 2 candidates
+            [[ $elem == *'...' ]] || break
+        done
+    done
+`
+
+// bash completion script using complete -F
+func (c *Completion) Bash(w io.Writer) {
+	f := c.Func_name()
+	fmt.Fprintf(w, "# bash completion for %s, generated by docopts completion\n", c.Name)
+	fmt.Fprintf(w, "%s() {\n", f)
+	fmt.Fprintf(w, "    local cur=${COMP_WORDS[COMP_CWORD]}\n")
+	fmt.Fprintf(w, "    local prev=${COMP_WORDS[COMP_CWORD-1]}\n")
+	fmt.Fprintf(w, "    local options=( %s )\n", bash_words(c.Options))
+	fmt.Fprintf(w, "    local options_with_arg=( %s )\n", bash_words(c.Options_with_arg))
+	fmt.Fprintf(w, "    local sequences=( %s )\n", bash_words(c.Sequences))
+	fmt.Fprint(w, `    local positional=() candidates=() elems=()
+    local i j w seq elem ok files=false skip=false
+
+    # option argument
+    for w in "${options_with_arg[@]}" ; do
+        if [[ $prev == "$w" ]] ; then
+            COMPREPLY=( $(compgen -f -- "$cur") )
+            return
+        fi
+    done
+
+    if [[ $cur == -* ]] ; then
+        COMPREPLY=( $(compgen -W "${options[*]}" -- "$cur") )
+        return
+    fi
+
+    # positional words already typed
+    for (( i = 1 ; i < COMP_CWORD ; i++ )) ; do
+        w=${COMP_WORDS[i]}
+        if $skip ; then
+            skip=false
+            continue
+        fi
+        if [[ $w == -* ]] ; then
+            for elem in "${options_with_arg[@]}" ; do
+                [[ $w == "$elem" ]] && skip=true
+            done
+            continue
+        fi
+        positional+=( "$w" )
+    done
+
+`)
+	fmt.Fprint(w, sh_match_sequences)
+	fmt.Fprint(w, `
+    COMPREPLY=( $(compgen -W "${candidates[*]}" -- "$cur") )
+    if $files ; then
+        COMPREPLY+=( $(compgen -f -- "$cur") )
+    fi
+}
+`)
+	fmt.Fprintf(w, "complete -F %s %s\n", f, c.Name)
+}
+
+// zsh description for _describe: name:description
+func zsh_describe(name, description string) string {
+	return strings.Replace(name, ":", `\:`, -1) + ":" + description
+}
+
+// zsh completion script, can be sourced or installed in $fpath
+func (c *Completion) Zsh(w io.Writer) {
+	f := c.Func_name()
+	described := make([]string, len(c.Options))
+	for i, o := range c.Options {
+		described[i] = zsh_describe(o, c.Descriptions[o])
+	}
+	fmt.Fprintf(w, "#compdef %s\n", c.Name)
+	fmt.Fprintf(w, "# zsh completion for %s, generated by docopts completion\n", c.Name)
+	fmt.Fprintf(w, "%s() {\n", f)
+	fmt.Fprintf(w, "    local -a options=( %s )\n", bash_words(described))
+	fmt.Fprintf(w, "    local -a options_with_arg=( %s )\n", bash_words(c.Options_with_arg))
+	fmt.Fprintf(w, "    local -a sequences=( %s )\n", bash_words(c.Sequences))
+	fmt.Fprint(w, `    local -a positional candidates elems
+    local cur=${words[CURRENT]} prev=${words[CURRENT-1]}
+    local i j w seq elem ok files=false skip=false
+    # same indexing and word splitting as bash
+    setopt localoptions ksharrays shwordsplit
+
+    # option argument
+    for w in "${options_with_arg[@]}" ; do
+        if [[ $prev == "$w" ]] ; then
+            _files
+            return
+        fi
+    done
+
+    if [[ $cur == -* ]] ; then
+        _describe -t options 'option' options
+        return
+    fi
+
+    # positional words already typed, words[0] is the command
+    for (( i = 1 ; i < CURRENT - 1 ; i++ )) ; do
+        w=${words[i]}
+        if $skip ; then
+            skip=false
+            continue
+        fi
+        if [[ $w == -* ]] ; then
+            for elem in "${options_with_arg[@]}" ; do
+                [[ $w == "$elem" ]] && skip=true
+            done
+            continue
+        fi
+        positional+=( "$w" )
+    done
+
+`)
+	fmt.Fprint(w, sh_match_sequences)
+	fmt.Fprint(w, `
+    (( ${#candidates[@]} )) && compadd -a candidates
+    if $files ; then
+        _files
+    fi
+}
+`)
+	fmt.Fprintf(w, "if [[ ${zsh_eval_context[-1]} == loadautofunc ]] ; then\n")
+	fmt.Fprintf(w, "    %s \"$@\"\nelse\n    compdef %s %s\nfi\n", f, f, c.Name)
+}
+
+// fish single quoted string
+func Fish_quote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return "'" + strings.Replace(s, "'", `\'`, -1) + "'"
+}
+
+func fish_words(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = Fish_quote(w)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// fish completion script using complete -c
+func (c *Completion) Fish(w io.Writer) {
+	f := c.Func_name()
+	fmt.Fprintf(w, "# fish completion for %s, generated by docopts completion\n", c.Name)
+	fmt.Fprintf(w, "function %s_next\n", f)
+	fmt.Fprintf(w, "    set -l options_with_arg %s\n", fish_words(c.Options_with_arg))
+	fmt.Fprintf(w, "    set -l sequences %s\n", fish_words(c.Sequences))
+	fmt.Fprint(w, `    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    set -l positional
+    set -l skip 0
+    for w in $tokens
+        if test $skip -eq 1
+            set skip 0
+            continue
+        end
+        if string match -q -- '-*' $w
+            contains -- $w $options_with_arg; and set skip 1
+            continue
+        end
+        set -a positional $w
+    end
+    for seq in $sequences
+        set -l elems (string split ' ' -- $seq)
+        set -l j 1
+        set -l ok 1
+        for w in $positional
+            if test $j -gt (count $elems)
+                set ok 0
+                break
+            end
+            if string match -q -- '<*>...' $elems[$j]
+                # repeatable argument, may be followed by a command
+                if test $j -lt (count $elems); and test $elems[(math $j + 1)] = $w
+                    set j (math $j + 2)
+                end
+            else if string match -q -- '<*>' $elems[$j]
+                set j (math $j + 1)
+            else if test $elems[$j] = $w
+                set j (math $j + 1)
+            else
+                set ok 0
+                break
+            end
+        end
+        test $ok -eq 1; or continue
+        test $j -le (count $elems); or continue
+        echo $elems[$j]
+        if string match -q -- '<*>...' $elems[$j]; and test $j -lt (count $elems)
+            echo $elems[(math $j + 1)]
+        end
+    end
+end
+
+`)
+	fmt.Fprintf(w, "complete -c %s -f\n", c.Name)
+	fmt.Fprintf(w, "complete -c %s -n 'not string match -q -- \"-*\" (commandline -ct)' -a '(%s_next | string match -v \"<*\")'\n", c.Name, f)
+	fmt.Fprintf(w, "complete -c %s -n '%s_next | string match -q \"<*\"' -F\n", c.Name, f)
+
+	with_arg := make(map[string]bool)
+	for _, o := range c.Options_with_arg {
+		with_arg[o] = true
+	}
+	for _, o := range c.Options {
+		flag := "-s"
+		name := o[1:]
+		if strings.HasPrefix(o, "--") {
+			flag = "-l"
+			name = o[2:]
+		}
+		line := fmt.Sprintf("complete -c %s %s %s", c.Name, flag, Fish_quote(name))
+		if with_arg[o] {
+			line += " -r -F"
+		}
+		if d := c.Descriptions[o]; d != "" {
+			line += " -d " + Fish_quote(d)
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+func init() {
+	Register_verb(&Verb{
+		Name:    "completion",
+		Summary: "Generate a shell completion script from USAGE.",
+		Usage: `Generate a shell completion script from USAGE.
+
+The completion knows commands, options, options which take an argument and
+positional arguments which are completed as filenames.
+
+Usage:
+  docopts completion [options] (bash|zsh|fish) USAGE
+
+Arguments:
+  USAGE                      The help message in docopt format.
+                             If - is given, read it from standard input.
+
+Options:
+  -h, --help                 Show this help.
+  -n <name>, --name=<name>   The command name to complete, default is the
+                             program name of USAGE.
+
+Examples:
+  source <(docopts completion bash "$(myscript --help)")
+  docopts completion fish "$usage" > ~/.config/fish/completions/myscript.fish
+`,
+		Run: func(v *Verb, argv []string) {
+			arguments := v.Parse(argv)
+			doc := arguments["USAGE"].(string)
+			if doc == "-" {
+				bytes, _ := ioutil.ReadAll(os.Stdin)
+				doc = string(bytes)
+			}
+			parsed, err := grammar.Parse_doc(strings.TrimSpace(doc))
+			if err != nil {
+				docopts_error("completion: USAGE: %v", err)
+			}
+			name, _ := arguments.String("--name")
+			c := New_completion(parsed, name)
+			switch {
+			case arguments["bash"].(bool):
+				c.Bash(out)
+			case arguments["zsh"].(bool):
+				c.Zsh(out)
+			case arguments["fish"].(bool):
+				c.Fish(out)
+			}
+		},
+	})
+}
@@ -0,0 +1,109 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for completion.go
+//
+package main
+
+import (
+	"bytes"
+	"github.com/docopt/docopts/pkg/grammar"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNew_completion(t *testing.T) {
+	doc, err := grammar.Parse_doc(`Usage:
+  naval_fate ship new <name>...
+  naval_fate ship <name> move <x> <y> [--speed=<kn>]
+  naval_fate mine (set|remove) [<x> <y>]
+  naval_fate cp SRC... dest <dst>
+  naval_fate -h | --help
+
+Options:
+  -h --help     Show this screen.
+  --speed=<kn>  Speed in knots [default: 10].`)
+	if err != nil {
+		t.Fatalf("Parse_doc error: %v", err)
+	}
+
+	c := New_completion(doc, "")
+	if c.Name != "naval_fate" {
+		t.Errorf("New_completion Name got: '%s'", c.Name)
+	}
+
+	expect_options := []string{"-h", "--help", "--speed"}
+	if !reflect.DeepEqual(c.Options, expect_options) {
+		t.Errorf("Options\ngot: '%#v'\nwant: '%#v'\n", c.Options, expect_options)
+	}
+	expect_with_arg := []string{"--speed"}
+	if !reflect.DeepEqual(c.Options_with_arg, expect_with_arg) {
+		t.Errorf("Options_with_arg\ngot: '%#v'\nwant: '%#v'\n", c.Options_with_arg, expect_with_arg)
+	}
+	if c.Descriptions["--speed"] != "Speed in knots [default: 10]." {
+		t.Errorf("Descriptions --speed got: '%s'", c.Descriptions["--speed"])
+	}
+
+	expect_sequences := []string{
+		"",
+		"cp <SRC>... dest <dst>",
+		"mine remove",
+		"mine remove <x>",
+		"mine remove <x> <y>",
+		"mine remove <y>",
+		"mine set",
+		"mine set <x>",
+		"mine set <x> <y>",
+		"mine set <y>",
+		"ship <name> move <x> <y>",
+		"ship new <name>...",
+	}
+	if !reflect.DeepEqual(c.Sequences, expect_sequences) {
+		t.Errorf("Sequences\ngot: '%#v'\nwant: '%#v'\n", c.Sequences, expect_sequences)
+	}
+
+	// an option described twice is completed once
+	twice, _ := grammar.Parse_doc("Usage: prog [options]\n\nOptions:\n  -v --verbose  Verbose.\n  --verbose     Again.")
+	if c := New_completion(twice, ""); !reflect.DeepEqual(c.Options, []string{"-v", "--verbose"}) {
+		t.Errorf("Options described twice got: '%#v'", c.Options)
+	}
+
+	c = New_completion(doc, "my-tool")
+	if c.Func_name() != "_my_tool_docopts_complete" {
+		t.Errorf("Func_name got: '%s'", c.Func_name())
+	}
+
+	// scripts are registered for the command name
+	tables := []struct {
+		generate func(*Completion, *bytes.Buffer)
+		expect   string
+	}{
+		{func(c *Completion, b *bytes.Buffer) { c.Bash(b) }, "complete -F _my_tool_docopts_complete my-tool\n"},
+		{func(c *Completion, b *bytes.Buffer) { c.Zsh(b) }, "compdef _my_tool_docopts_complete my-tool\n"},
+		{func(c *Completion, b *bytes.Buffer) { c.Fish(b) }, "complete -c my-tool -l 'speed' -r -F -d 'Speed in knots [default: 10].'\n"},
+	}
+	for _, table := range tables {
+		buf := new(bytes.Buffer)
+		table.generate(c, buf)
+		if !strings.Contains(buf.String(), table.expect) {
+			t.Errorf("completion script doesn't contain: '%s'\ngot: '%s'", table.expect, buf.String())
+		}
+	}
+}
+
+func TestFish_quote(t *testing.T) {
+	tables := []struct {
+		input  string
+		expect string
+	}{
+		{"pipo", "'pipo'"},
+		{"it's", `'it\'s'`},
+		{`back\slash`, `'back\\slash'`},
+	}
+
+	for _, table := range tables {
+		if res := Fish_quote(table.input); res != table.expect {
+			t.Errorf("Fish_quote for '%s', got: %v, want: %v.", table.input, res, table.expect)
+		}
+	}
+}
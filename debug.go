@@ -0,0 +1,226 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// debug.go: explain how docopts understands a usage and how argv matches it.
+//
+// The explanation is written apart from the evalable output, on stderr, see
+// the --debug option and the debug verb.
+//
+package main
+
+import (
+	"fmt"
+	"github.com/docopt/docopt-go"
+	"github.com/docopt/docopts/pkg/grammar"
+	"github.com/docopt/docopts/pkg/shellout"
+	"io"
+	"strings"
+)
+
+const debug_header = "################## %s ##################\n"
+
+// Debug_parsed outputs the parsed usage: patterns tree, options section with
+// defaults and the options standing for [options].
+func Debug_parsed(w io.Writer, d *grammar.Doc) {
+	fmt.Fprintf(w, debug_header, "usage")
+	fmt.Fprintf(w, "%20s : %s\n", "program", d.Prog)
+	for i, line := range d.Usage_lines {
+		fmt.Fprintf(w, "%20s : %s\n", fmt.Sprintf("pattern %d", i+1), line)
+	}
+
+	fmt.Fprintf(w, debug_header, "pattern tree")
+	debug_tree(w, d.Pattern, 0)
+
+	fmt.Fprintf(w, debug_header, "options")
+	for _, o := range d.Options {
+		fmt.Fprintf(w, "%20s : %s\n", strings.Join(o.Names(), ", "), debug_option(o))
+	}
+}
+
+func debug_option(o *grammar.Option) string {
+	var desc string
+	if o.Argcount == 0 {
+		desc = "no argument"
+	} else {
+		desc = "argument " + o.Arg_name
+		if o.Has_default {
+			desc += fmt.Sprintf(", default: '%s'", o.Default)
+		}
+		if o.Type != "" {
+			desc += ", type: " + o.Type
+			if o.Exists {
+				desc += ", exists"
+			}
+		}
+		if o.Choices != nil {
+			desc += ", choices: " + strings.Join(o.Choices, " ")
+		}
+		if o.Pattern != "" {
+			desc += ", pattern: " + o.Pattern
+		}
+		if o.Env != "" {
+			desc += ", env: " + o.Env
+		}
+	}
+	if !o.Described {
+		desc += ", only found in usage"
+	}
+	return desc
+}
+
+func debug_tree(w io.Writer, p *grammar.Pattern, depth int) {
+	indent := strings.Repeat("  ", depth+1)
+	switch {
+	case p.Is_leaf():
+		fmt.Fprintf(w, "%s%s %s\n", indent, p.Type, p)
+	case p.Type == grammar.Options_shortcut:
+		names := make([]string, len(p.Children))
+		for i, c := range p.Children {
+			names[i] = c.Name
+		}
+		fmt.Fprintf(w, "%s%s [options] stands for: %s\n", indent, p.Type, strings.Join(names, " "))
+	default:
+		fmt.Fprintf(w, "%s%s\n", indent, p.Type)
+		for _, c := range p.Children {
+			debug_tree(w, c, depth+1)
+		}
+	}
+}
+
+// Debug_argument_match outputs how argv is split into options and arguments,
+// then how it is matched against each usage pattern and which token matched
+// which leaf.
+func Debug_argument_match(w io.Writer, d *grammar.Doc, argv []string, options_first bool) {
+	fmt.Fprintf(w, debug_header, "argv")
+	items, err := d.Parse_argv(argv, options_first)
+	if err != nil {
+		fmt.Fprintf(w, "error: %v\n", err)
+		return
+	}
+	for _, item := range items {
+		fmt.Fprintf(w, "%20s : %s\n", item.Token, debug_item(item))
+	}
+
+	fmt.Fprintf(w, debug_header, "matching")
+	var winner *grammar.Match
+	winner_index := 0
+	for i, p := range d.Usage_patterns() {
+		fmt.Fprintf(w, "pattern %d: %s\n", i+1, d.Usage_lines[i])
+		m := p.Match_trace(items, func(depth int, p *grammar.Pattern, m *grammar.Match) {
+			debug_trace(w, depth, p, m)
+		})
+		switch {
+		case m.Matched && len(m.Left) == 0:
+			fmt.Fprintf(w, "=> pattern %d matched\n", i+1)
+			if winner == nil {
+				winner, winner_index = m, i+1
+			}
+		case m.Matched:
+			fmt.Fprintf(w, "=> pattern %d rejected, not matched: %s\n", i+1, debug_items(m.Left))
+		default:
+			fmt.Fprintf(w, "=> pattern %d rejected, missing: %s\n", i+1, m.Missing)
+		}
+	}
+
+	fmt.Fprintf(w, debug_header, "argument match")
+	if winner == nil {
+		fmt.Fprintf(w, "no pattern matched: %v\n", d.Explain(argv, options_first))
+		return
+	}
+	fmt.Fprintf(w, "pattern %d: %s\n", winner_index, d.Usage_lines[winner_index-1])
+	for i, leaf := range winner.Leaves {
+		fmt.Fprintf(w, "%20s : %s %s\n", winner.Items[i].Token, leaf.Type, leaf.Name)
+	}
+}
+
+func debug_item(item *grammar.Item) string {
+	if item.Option == nil {
+		return fmt.Sprintf("argument '%s'", item.Value)
+	}
+	if item.Option.Argcount > 0 {
+		return fmt.Sprintf("option %s = '%s'", item.Option.Name(), item.Value)
+	}
+	return fmt.Sprintf("option %s", item.Option.Name())
+}
+
+func debug_items(items []*grammar.Item) string {
+	tokens := make([]string, len(items))
+	for i, item := range items {
+		tokens[i] = item.String()
+	}
+	return strings.Join(tokens, " ")
+}
+
+// one matching step: leaves are displayed with their result, branches when
+// entered and left
+func debug_trace(w io.Writer, depth int, p *grammar.Pattern, m *grammar.Match) {
+	indent := strings.Repeat("  ", depth+1)
+	if p.Is_leaf() {
+		if m == nil {
+			return
+		}
+		if m.Matched {
+			fmt.Fprintf(w, "%s%s %s: matched '%s'\n", indent, p.Type, p, m.Items[0].Token)
+		} else {
+			fmt.Fprintf(w, "%s%s %s: no match\n", indent, p.Type, p)
+		}
+		return
+	}
+	if m == nil {
+		fmt.Fprintf(w, "%s%s %s\n", indent, p.Type, p)
+		return
+	}
+	result := "failed"
+	if m.Matched {
+		result = "matched"
+	}
+	fmt.Fprintf(w, "%s%s: %s\n", indent, p.Type, result)
+}
+
+// Debug_sources outputs where each option's value comes from, args are nil
+// on error.
+func Debug_sources(w io.Writer, sources []*shellout.Option_source, args docopt.Opts) {
+	fmt.Fprintf(w, debug_header, "option sources")
+	for _, s := range sources {
+		if args == nil {
+			fmt.Fprintf(w, "%20s : %s\n", s.Option.Name(), s.From)
+		} else {
+			fmt.Fprintf(w, "%20s : %s = %v\n", s.Option.Name(), s.From, args[s.Option.Name()])
+		}
+	}
+}
+
+// Debug outputs all parts requested of the explanation, doc parsing error included.
+func Debug(w io.Writer, doc string, argv []string, options_first, explain_parsed, show_match bool) {
+	d, err := grammar.Parse_doc(doc)
+	if err != nil {
+		fmt.Fprintf(w, "error: USAGE: %v\n", err)
+		return
+	}
+	if explain_parsed {
+		Debug_parsed(w, d)
+	}
+	if show_match {
+		Debug_argument_match(w, d, argv, options_first)
+	}
+}
+
+func init() {
+	Register_verb(&Verb{
+		Name:    "debug",
+		Summary: "Explain how <argv> matches USAGE, on standard error.",
+		Usage: Parse_verb_usage("debug", `Explain how <argv> matches USAGE, on standard error.
+
+The parsed USAGE is displayed: patterns tree, options with their default and
+what [options] stands for. Then each usage pattern is tried against <argv>,
+each leaf is displayed with the token it matched. Standard output is the same
+as the parse verb, so it can still be evaluated.
+`, `  --explain-parsed              Only explain the parsed USAGE.
+  --show-argument-match         Only show how <argv> matches USAGE.
+`),
+		Run: func(v *Verb, argv []string) {
+			arguments := v.Parse(argv)
+			arguments["--debug"] = true
+			docopts_parse(arguments, arguments["USAGE"].(string))
+		},
+	})
+}
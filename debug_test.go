@@ -0,0 +1,92 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for debug.go
+//
+package main
+
+import (
+	"bytes"
+	"github.com/docopt/docopt-go"
+	"github.com/docopt/docopts/pkg/grammar"
+	"github.com/docopt/docopts/pkg/shellout"
+	"strings"
+	"testing"
+)
+
+var debug_doc = `Usage:
+  prog ship new <name>...
+  prog [options] mine (set|remove)
+
+Options:
+  --speed=<kn>  Speed [default: 10].
+  -v  Verbose.`
+
+func TestDebug_parsed(t *testing.T) {
+	d, _ := grammar.Parse_doc(debug_doc)
+	var buf bytes.Buffer
+	Debug_parsed(&buf, d)
+	res := buf.String()
+	for _, expect := range []string{
+		"           pattern 2 : prog [options] mine (set|remove)\n",
+		"        OneOrMore\n          Argument <name>\n",
+		"          OptionsShortcut [options] stands for: --speed -v\n",
+		"             --speed : argument <kn>, default: '10'\n",
+		"                  -v : no argument\n",
+	} {
+		if !strings.Contains(res, expect) {
+			t.Errorf("Debug_parsed output doesn't contain: '%s'\n%s", expect, res)
+		}
+	}
+}
+
+func TestDebug_argument_match(t *testing.T) {
+	d, _ := grammar.Parse_doc(debug_doc)
+	var buf bytes.Buffer
+	Debug_argument_match(&buf, d, []string{"-v", "mine", "set"}, false)
+	res := buf.String()
+	for _, expect := range []string{
+		"                  -v : option -v\n",
+		"                mine : argument 'mine'\n",
+		"=> pattern 1 rejected, missing: ship\n",
+		"    Command mine: matched 'mine'\n",
+		"=> pattern 2 matched\n",
+		"pattern 2: prog [options] mine (set|remove)\n                  -v : Option -v\n",
+	} {
+		if !strings.Contains(res, expect) {
+			t.Errorf("Debug_argument_match output doesn't contain: '%s'\n%s", expect, res)
+		}
+	}
+
+	buf.Reset()
+	Debug_argument_match(&buf, d, []string{"ship"}, false)
+	if !strings.Contains(buf.String(), "no pattern matched: Missing command 'new'\n") {
+		t.Errorf("Debug_argument_match no match got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	Debug_argument_match(&buf, d, []string{"--sp"}, false)
+	if !strings.HasSuffix(buf.String(), "error: --speed requires a value\n") {
+		t.Errorf("Debug_argument_match argv error got:\n%s", buf.String())
+	}
+}
+
+func TestDebug_sources(t *testing.T) {
+	sources := []*shellout.Option_source{
+		{Option: grammar.Parse_option("--token=<t>  [env: MYTOOL_TOKEN]"), From: "env MYTOOL_TOKEN"},
+		{Option: grammar.Parse_option("-v"), From: "argv"},
+	}
+	var buf bytes.Buffer
+	Debug_sources(&buf, sources, docopt.Opts{"--token": "secret", "-v": true})
+	expect := "################## option sources ##################\n" +
+		"             --token : env MYTOOL_TOKEN = secret\n" +
+		"                  -v : argv = true\n"
+	if buf.String() != expect {
+		t.Errorf("Debug_sources got:\n%s\nwant:\n%s", buf.String(), expect)
+	}
+
+	buf.Reset()
+	Debug_sources(&buf, sources, nil)
+	if !strings.HasSuffix(buf.String(), "             --token : env MYTOOL_TOKEN\n                  -v : argv\n") {
+		t.Errorf("Debug_sources without args got:\n%s", buf.String())
+	}
+}
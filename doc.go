@@ -0,0 +1,276 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// doc.go: generate reference documentation, Markdown or HTML, from a docopt
+// usage.
+//
+package main
+
+import (
+	"fmt"
+	"github.com/docopt/docopts/pkg/grammar"
+	"html"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Reference documentation of a parsed usage.
+type Doc_page struct {
+	Doc *grammar.Doc
+	// program name, the usage's program name if empty
+	Name string
+	// the Version section, omitted if empty
+	Version string
+	// level of the top heading, 1 if 0
+	Level int
+}
+
+// One row of the options table.
+type Doc_option struct {
+	Short, Long, Argument, Default, Description string
+}
+
+// Options returns the described options, the default is removed from the
+// description.
+func (p *Doc_page) Options() []*Doc_option {
+	options := []*Doc_option{}
+	for _, o := range p.Doc.Options {
+		if !o.Described {
+			continue
+		}
+		row := &Doc_option{
+			Short:       o.Short,
+			Long:        o.Long,
+			Argument:    o.Arg_name,
+			Description: strings.TrimSpace(re_man_default.ReplaceAllString(o.Description, "")),
+		}
+		if o.Has_default {
+			row.Default = o.Default
+		}
+		options = append(options, row)
+	}
+	return options
+}
+
+func (p *Doc_page) defaults() {
+	if p.Name == "" {
+		p.Name = p.Doc.Prog
+	}
+	if p.Level == 0 {
+		p.Level = 1
+	}
+}
+
+// paragraphs of the free text, the first one is the summary
+func doc_paragraphs(free []string) [][]string {
+	paragraphs := [][]string{}
+	current := []string{}
+	for _, line := range append(free, "") {
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				paragraphs = append(paragraphs, current)
+			}
+			current = []string{}
+			continue
+		}
+		current = append(current, strings.TrimSpace(line))
+	}
+	return paragraphs
+}
+
+// a Markdown table cell: one line, pipes escaped
+func md_cell(s string) string {
+	return strings.Replace(strings.Replace(s, "|", `\|`, -1), "\n", " ", -1)
+}
+
+func md_code(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + md_cell(s) + "`"
+}
+
+// Markdown outputs the documentation: the free text, Synopsis code block,
+// Commands list, Options table, the other sections of the help message as
+// written, then Version.
+func (p *Doc_page) Markdown(w io.Writer) {
+	p.defaults()
+	h := strings.Repeat("#", p.Level)
+	free, sections := Doc_sections(p.Doc.Text)
+	fmt.Fprintf(w, "%s %s\n", h, p.Name)
+	for _, paragraph := range doc_paragraphs(free) {
+		fmt.Fprintf(w, "\n%s\n", strings.Join(paragraph, "\n"))
+	}
+
+	fmt.Fprintf(w, "\n%s# Synopsis\n\n```\n%s\n```\n", h, strings.Join(p.Doc.Usage_lines, "\n"))
+
+	if commands := p.Doc.Commands(); len(commands) > 0 {
+		fmt.Fprintf(w, "\n%s# Commands\n\n", h)
+		for _, c := range commands {
+			fmt.Fprintf(w, "- `%s`\n", c)
+		}
+	}
+
+	if options := p.Options(); len(options) > 0 {
+		fmt.Fprintf(w, "\n%s# Options\n\n", h)
+		fmt.Fprintln(w, "| Short | Long | Argument | Default | Description |")
+		fmt.Fprintln(w, "|-------|------|----------|---------|-------------|")
+		for _, o := range options {
+			fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n",
+				md_code(o.Short), md_code(o.Long), md_code(o.Argument), md_code(o.Default), md_cell(o.Description))
+		}
+	}
+
+	for _, s := range sections {
+		lines := trim_blank_lines(dedent(s.Lines))
+		if len(lines) > 0 {
+			fmt.Fprintf(w, "\n%s# %s\n\n```\n%s\n```\n", h, s.Title, strings.Join(lines, "\n"))
+		}
+	}
+
+	if p.Version != "" {
+		fmt.Fprintf(w, "\n%s# Version\n\n```\n%s\n```\n", h, strings.TrimSpace(p.Version))
+	}
+}
+
+// Html outputs the same documentation as Markdown, as an HTML fragment.
+func (p *Doc_page) Html(w io.Writer) {
+	p.defaults()
+	e := html.EscapeString
+	heading := func(level int, title string) {
+		if level > 6 {
+			level = 6
+		}
+		fmt.Fprintf(w, "<h%d>%s</h%d>\n", level, e(title), level)
+	}
+	pre := func(lines []string) {
+		fmt.Fprintf(w, "<pre><code>%s</code></pre>\n", e(strings.Join(lines, "\n")))
+	}
+	code := func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return "<code>" + e(s) + "</code>"
+	}
+
+	free, sections := Doc_sections(p.Doc.Text)
+	heading(p.Level, p.Name)
+	for _, paragraph := range doc_paragraphs(free) {
+		fmt.Fprintf(w, "<p>%s</p>\n", e(strings.Join(paragraph, "\n")))
+	}
+
+	heading(p.Level+1, "Synopsis")
+	pre(p.Doc.Usage_lines)
+
+	if commands := p.Doc.Commands(); len(commands) > 0 {
+		heading(p.Level+1, "Commands")
+		fmt.Fprintln(w, "<ul>")
+		for _, c := range commands {
+			fmt.Fprintf(w, "<li>%s</li>\n", code(c))
+		}
+		fmt.Fprintln(w, "</ul>")
+	}
+
+	if options := p.Options(); len(options) > 0 {
+		heading(p.Level+1, "Options")
+		fmt.Fprintln(w, "<table>")
+		fmt.Fprintln(w, "<tr><th>Short</th><th>Long</th><th>Argument</th><th>Default</th><th>Description</th></tr>")
+		for _, o := range options {
+			fmt.Fprintf(w, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+				code(o.Short), code(o.Long), code(o.Argument), code(o.Default), e(o.Description))
+		}
+		fmt.Fprintln(w, "</table>")
+	}
+
+	for _, s := range sections {
+		lines := trim_blank_lines(dedent(s.Lines))
+		if len(lines) > 0 {
+			heading(p.Level+1, s.Title)
+			pre(lines)
+		}
+	}
+
+	if p.Version != "" {
+		heading(p.Level+1, "Version")
+		pre(strings.Split(strings.TrimSpace(p.Version), "\n"))
+	}
+}
+
+func init() {
+	Register_verb(&Verb{
+		Name:    "doc",
+		Summary: "Generate Markdown or HTML documentation from USAGE.",
+		Usage: `Generate reference documentation from USAGE, in Markdown or HTML.
+
+The free text of USAGE comes first, then the Synopsis: the usage patterns, the
+Commands list and the Options table: short and long names, argument, default
+and description. Other sections of USAGE, like Arguments or Examples, are kept
+as written. The version message is the Version section.
+
+Usage:
+  docopts doc [options] USAGE
+  docopts doc [options] -f FILENAME
+
+Arguments:
+  USAGE                      The help message in docopt format.
+                             If - is given, read it from standard input.
+
+Options:
+  -h, --help                 Show this help.
+  --html                     Output an HTML fragment instead of Markdown.
+  -f, --file=FILENAME        Read the help message and the version from the
+                             comments of the script FILENAME, see: docopts
+                             extract --help.
+  -n <name>, --name=<name>   The program name, the title, default is the
+                             program name of USAGE.
+  -V <msg>, --version=<msg>  The version message.
+  -l <n>, --level=<n>        Level of the title heading, sections are one
+                             level below. [default: 1]
+
+Examples:
+  docopts doc -f myscript > docs/myscript.md
+  docopts doc --html --level=2 "$usage" > myscript.html
+`,
+		Run: func(v *Verb, argv []string) {
+			arguments := v.Parse(argv)
+			p := &Doc_page{}
+			var doc string
+			if filename, _ := arguments.String("--file"); filename != "" {
+				bytes, err := ioutil.ReadFile(filename)
+				if err != nil {
+					docopts_error("doc: %v", err)
+				}
+				doc, err = Extract_usage(string(bytes))
+				if err != nil {
+					docopts_error("doc: %v", fmt.Errorf("%s: %v", filename, err))
+				}
+				p.Version = Extract_version(string(bytes))
+			} else {
+				doc = arguments["USAGE"].(string)
+				if doc == "-" {
+					bytes, _ := ioutil.ReadAll(os.Stdin)
+					doc = string(bytes)
+				}
+			}
+			parsed, err := grammar.Parse_doc(strings.TrimSpace(doc))
+			if err != nil {
+				docopts_error("doc: USAGE: %v", err)
+			}
+			p.Doc = parsed
+			p.Name, _ = arguments.String("--name")
+			if version, _ := arguments.String("--version"); version != "" {
+				p.Version = version
+			}
+			p.Level, err = arguments.Int("--level")
+			if err != nil || p.Level < 1 {
+				docopts_error("doc: %v", fmt.Errorf("--level: a number from 1 is expected"))
+			}
+			if arguments["--html"].(bool) {
+				p.Html(out)
+			} else {
+				p.Markdown(out)
+			}
+		},
+	})
+}
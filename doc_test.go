@@ -0,0 +1,90 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for doc.go
+//
+package main
+
+import (
+	"bytes"
+	"github.com/docopt/docopts/pkg/grammar"
+	"strings"
+	"testing"
+)
+
+var doc_usage = `Naval Fate, ship management.
+
+Moves ships.
+
+Usage:
+  naval_fate ship <name> move [--speed=<kn>]
+  naval_fate -h | --help
+
+Arguments:
+  <name>  Ship name.
+
+Options:
+  -h --help     Show this screen.
+  --speed=<kn>  Speed in knots | nautical [default: 10].
+`
+
+func TestDoc_page_Markdown(t *testing.T) {
+	doc, err := grammar.Parse_doc(doc_usage)
+	if err != nil {
+		t.Fatalf("Parse_doc: %v", err)
+	}
+	var out bytes.Buffer
+	p := &Doc_page{Doc: doc, Version: "naval_fate 2.0"}
+	p.Markdown(&out)
+	expect := "# naval_fate\n" +
+		"\nNaval Fate, ship management.\n" +
+		"\nMoves ships.\n" +
+		"\n## Synopsis\n\n```\n" +
+		"naval_fate ship <name> move [--speed=<kn>]\n" +
+		"naval_fate -h | --help\n" +
+		"```\n" +
+		"\n## Commands\n\n- `ship`\n- `move`\n" +
+		"\n## Options\n\n" +
+		"| Short | Long | Argument | Default | Description |\n" +
+		"|-------|------|----------|---------|-------------|\n" +
+		"| `-h` | `--help` |  |  | Show this screen. |\n" +
+		"|  | `--speed` | `<kn>` | `10` | Speed in knots \\| nautical. |\n" +
+		"\n## Arguments\n\n```\n<name>  Ship name.\n```\n" +
+		"\n## Version\n\n```\nnaval_fate 2.0\n```\n"
+	if out.String() != expect {
+		t.Errorf("Markdown\ngot: '%s'\nwant: '%s'", out.String(), expect)
+	}
+
+	// name and level given, no commands, no version
+	doc, _ = grammar.Parse_doc("Usage: prog [-v]\n\nOptions:\n  -v  Verbose.")
+	out.Reset()
+	p = &Doc_page{Doc: doc, Name: "other", Level: 3}
+	p.Markdown(&out)
+	if res := out.String(); !strings.HasPrefix(res, "### other\n\n#### Synopsis\n") ||
+		strings.Contains(res, "Commands") || strings.Contains(res, "Version") {
+		t.Errorf("Markdown with level 3 got: '%s'", res)
+	}
+}
+
+func TestDoc_page_Html(t *testing.T) {
+	doc, err := grammar.Parse_doc(doc_usage)
+	if err != nil {
+		t.Fatalf("Parse_doc: %v", err)
+	}
+	var out bytes.Buffer
+	p := &Doc_page{Doc: doc, Level: 6}
+	p.Html(&out)
+	res := out.String()
+	for _, expect := range []string{
+		"<h6>naval_fate</h6>\n<p>Naval Fate, ship management.</p>\n<p>Moves ships.</p>\n<h6>Synopsis</h6>\n",
+		"<pre><code>naval_fate ship &lt;name&gt; move [--speed=&lt;kn&gt;]\n",
+		"<li><code>ship</code></li>\n",
+		"<tr><td></td><td><code>--speed</code></td><td><code>&lt;kn&gt;</code></td><td><code>10</code></td><td>Speed in knots | nautical.</td></tr>\n",
+	} {
+		if !strings.Contains(res, expect) {
+			t.Errorf("Html got: '%s'\nmissing: '%s'", res, expect)
+		}
+	}
+	if strings.Contains(res, "Version") {
+		t.Errorf("Html without version got: '%s'", res)
+	}
+}
@@ -17,6 +17,10 @@
 #   # or for using globals variables (bash 3.2 compatible):
 #   source path/to/docopts.sh --auto -G "$@"
 #
+#   # or into an array chosen by the caller, even local (bash 4.3+ nameref):
+#   source path/to/docopts.sh
+#   docopt_parse_into myopts "$@" || exit $?
+#
 # Conventions:
 #   The prefix docopt_* is used to export globals and functions
 #   docopt_auto_parse() modify $HELP and $ARGS or populate $ARGS_* globals.
@@ -28,10 +32,21 @@
 #   source path/to/docopts.sh
 #   docopts -G ARGS -h "$help" -V $version : "$@"
 
+# true if the docopts found has the extract verb, an older docopts doesn't:
+# the helpers then fall back to their own awk and sed filters.
+__docopt_has_extract() {
+    [[ $(docopts extract --help 2> /dev/null) == *'docopts extract --usage'* ]]
+}
+
 # Doc:
 # fetch `Usage:` bloc from the given filename
 # usually $0 in the main level script
+# See: docopts extract --help, for the extraction rules.
 docopt_get_help_string() {
+    if __docopt_has_extract ; then
+        docopts extract --usage "$1"
+        return
+    fi
     local myfname=$1
     # filter the first block starting at a "# Usage:" and ending at an empty line
     # one level of comment markup is removed.
@@ -82,7 +97,9 @@ docopt_get_help_string() {
 #
 # Use standard delimiter ----
 docopt_get_version_string() {
-    if [[ -f "$1" ]] ; then
+    if [[ -f "$1" ]] && __docopt_has_extract ; then
+        docopts extract --version "$1"
+    elif [[ -f "$1" ]] ; then
         # filter the block (all blocks) starting at a "# Usage:" and ending
         # at an empty line, one level of comment markup is removed
         sed -n -e '/^# ----/,/^$/ { 1d; s/^# \{0,1\}//; /----/ d; p; }' < "$1"
@@ -116,6 +133,7 @@ docopt_get_values() {
 # Doc:
 # echo evaluable code to get all the values into a bash array
 # Usage: eval "$(docopt_get_eval_array ARGS FILE myarray)"
+# See also: docopts --arrays, which outputs the arrays directly.
 docopt_get_eval_array() {
     local ref="\${$1[$2,#]}"
     local nb_val=$(eval echo "$ref")
@@ -165,6 +183,33 @@ docopt_auto_parse() {
     return $res
 }
 
+# Doc:
+# Parse the arguments into the associative array named by $1, bash 4.3+.
+# The array can be local to the caller, at any function nesting level, it is
+# declared global if it doesn't exist.
+# Usage is $HELP, or the caller script's `Usage:` comment if $HELP is empty.
+# On --help or --version the message is displayed and the script exits 0, a
+# usage error returns 64. Names starting with __docopt_ are reserved.
+# Usage: docopt_parse_into myopts "$@" || exit $?
+docopt_parse_into() {
+    local -n __docopt_parse_into=$1
+    shift
+    local __docopt_help=${HELP:-$(docopt_get_help_string "${BASH_SOURCE[1]}")}
+    local __docopt_status
+    __docopt_eval "$(docopts --nameref=__docopt_parse_into -h "$__docopt_help" : "$@")"
+    __docopt_status=$?
+    if [[ $__docopt_status -eq 100 ]] ; then
+        # --help or --version displayed by docopts --nameref
+        exit 0
+    fi
+    return $__docopt_status
+}
+
+# evaluates docopts output in its own function, its return stops here
+__docopt_eval() {
+    eval "$1"
+}
+
 # Doc:
 # Extract the raw value of a parsed docopts output.
 # arguments:
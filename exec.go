@@ -0,0 +1,137 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// exec.go: docopts exec, parses <argv> and executes a command with the
+// parsed arguments as environment variables, no eval involved.
+//
+package main
+
+import (
+	"fmt"
+	"github.com/docopt/docopts/pkg/shellout"
+	"os"
+	"strings"
+)
+
+var Exec_usage = `Parse <argv> according to USAGE and execute <command> with the parsed
+arguments as environment variables, instead of outputting shell code to
+evaluate. Names are mangled as global variables, prefixed with --prefix.
+Repeatable arguments are joined with --export-separator.
+
+On --help or --version, the message is displayed and <command> isn't
+executed. On a usage error, the error and the usage are displayed on standard
+error and docopts exits with status 64.
+
+Usage:
+  docopts exec [options] USAGE -- <command>...
+
+Arguments:
+  USAGE                         The help message in docopt format, can also be
+                                given with -h <msg> as the legacy command line.
+  <command>...                  The command and its arguments, then : and the
+                                <argv> to parse: -- cmd [<arg>...] : [<argv>...]
+
+Options:
+  -h, --help                    Show this help.
+  -V <msg>, --version=<msg>     A version message.
+  -O, --options-first           Disallow interspersing options and positional
+                                arguments in <argv>.
+  -H, --no-help                 Don't handle --help and --version specially.
+  -G <prefix>, --prefix=<prefix>
+                                Prefix of the variables names:
+                                <prefix>_{mangled_args}={value}
+  --export-separator=<sep>      Separator of repeatable arguments.
+                                [default: :]
+  --config-option=<option>      The option of USAGE giving a config file,
+                                JSON, TOML or INI, whose values are used for
+                                options not given in <argv>.
+`
+
+// Moves -h <msg> or --help=<msg> before -- to the USAGE argument, as the
+// legacy command line gives the usage. -h alone is still the verb's help.
+func exec_argv(argv []string) []string {
+	end := len(argv)
+	for i, a := range argv {
+		if a == "--" {
+			end = i
+			break
+		}
+	}
+	usage := ""
+	options := make([]string, 0, len(argv))
+	for i := 0; i < end; i++ {
+		switch {
+		case (argv[i] == "-h" || argv[i] == "--help") && i+1 < end:
+			usage = argv[i+1]
+			i++
+		case strings.HasPrefix(argv[i], "--help="):
+			usage = strings.TrimPrefix(argv[i], "--help=")
+		default:
+			options = append(options, argv[i])
+		}
+	}
+	if usage == "" || end == len(argv) {
+		return argv
+	}
+	options = append(options, usage)
+	return append(options, argv[end:]...)
+}
+
+// Splits <command>... at the first ':' into the command and the <argv> to
+// parse.
+func exec_split_command(command []string) ([]string, []string, error) {
+	for i, a := range command {
+		if a == ":" {
+			if i == 0 {
+				return nil, nil, fmt.Errorf("no command before ':'")
+			}
+			return command[:i], command[i+1:], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("missing ':' between the command and <argv>")
+}
+
+func docopts_exec(v *Verb, argv []string) {
+	arguments := v.Parse(exec_argv(argv))
+	command, parsed_argv, err := exec_split_command(arguments["<command>"].([]string))
+	if err != nil {
+		docopts_error("exec: %v", err)
+	}
+
+	p := &shellout.Parser{
+		Options_first: arguments["--options-first"].(bool),
+		No_help:       arguments["--no-help"].(bool),
+	}
+	p.Version, _ = arguments.String("--version")
+	p.Config_option, _ = arguments.String("--config-option")
+	d := &shellout.Docopts{Mangle_key: true}
+	d.Global_prefix, _ = arguments.String("--prefix")
+	d.Export_separator, _ = arguments.String("--export-separator")
+
+	result, err := p.Parse(arguments["USAGE"].(string), parsed_argv)
+	if err != nil {
+		docopts_error("USAGE: %v", err)
+	}
+	if result.Error != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n%s\n", result.Error, result.Usage)
+		os.Exit(64)
+	}
+	if result.Message != "" {
+		fmt.Println(result.Message)
+		os.Exit(0)
+	}
+	env, err := d.Environ(result.Args)
+	if err != nil {
+		docopts_error("exec: %v", err)
+	}
+	err = exec_command(command, shellout.Merge_environ(os.Environ(), env))
+	docopts_error("exec: %v", err)
+}
+
+func init() {
+	Register_verb(&Verb{
+		Name:    "exec",
+		Summary: "Execute a command with the parsed <argv> as environment.",
+		Usage:   Exec_usage,
+		Run:     docopts_exec,
+	})
+}
@@ -0,0 +1,44 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for exec.go
+//
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExec_argv(t *testing.T) {
+	tables := []struct {
+		argv   []string
+		expect []string
+	}{
+		{[]string{"-h", "Usage: prog", "--prefix=OPT", "--", "cmd", ":", "a"},
+			[]string{"--prefix=OPT", "Usage: prog", "--", "cmd", ":", "a"}},
+		{[]string{"--help=Usage: prog", "--", "cmd", ":"},
+			[]string{"Usage: prog", "--", "cmd", ":"}},
+		// unchanged: USAGE given, verb's help, -h of the parsed argv
+		{[]string{"Usage: prog", "--", "cmd", ":", "-h", "x"},
+			[]string{"Usage: prog", "--", "cmd", ":", "-h", "x"}},
+		{[]string{"-h"}, []string{"-h"}},
+		{[]string{"-h", "Usage: prog"}, []string{"-h", "Usage: prog"}},
+	}
+	for _, table := range tables {
+		if got := exec_argv(table.argv); !reflect.DeepEqual(got, table.expect) {
+			t.Errorf("exec_argv(%q) got: %q want: %q", table.argv, got, table.expect)
+		}
+	}
+}
+
+func TestExec_split_command(t *testing.T) {
+	command, argv, err := exec_split_command([]string{"cmd", "-x", ":", "a", ":"})
+	if err != nil || !reflect.DeepEqual(command, []string{"cmd", "-x"}) || !reflect.DeepEqual(argv, []string{"a", ":"}) {
+		t.Errorf("exec_split_command got: %v %q %q", err, command, argv)
+	}
+	for _, bad := range [][]string{{"cmd", "a"}, {":", "a"}} {
+		if _, _, err := exec_split_command(bad); err == nil {
+			t.Errorf("exec_split_command(%q) expecting error", bad)
+		}
+	}
+}
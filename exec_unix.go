@@ -0,0 +1,20 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// exec_command replaces docopts with command, it only returns on error.
+func exec_command(command []string, env []string) error {
+	path, err := exec.LookPath(command[0])
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(path, command, env)
+}
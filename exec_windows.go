@@ -0,0 +1,28 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// exec_command runs command and exits with its status, there is no execve
+// on windows. It only returns on error.
+func exec_command(command []string, env []string) error {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Env = env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err := cmd.Run()
+	if exit_error, ok := err.(*exec.ExitError); ok {
+		os.Exit(exit_error.ExitCode())
+	}
+	if err != nil {
+		return err
+	}
+	os.Exit(0)
+	return nil
+}
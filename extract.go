@@ -0,0 +1,180 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// extract.go: docopts extract, the help message or the version of a script,
+// taken from its comments or a heredoc. Replaces the awk and sed of
+// docopts.sh's docopt_get_help_string() and docopt_get_version_string().
+//
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Comment markers of the usage block: shell, C-like and SQL or Lua.
+var Comment_markers = []string{"#", "//", "--"}
+
+// a heredoc opening: <<EOF, <<-EOF, <<'EOF' or <<"EOF", not a here-string <<<
+var re_heredoc = regexp.MustCompile(`(?:^|[^<])<<(-?)\s*['"]?([A-Za-z_][A-Za-z0-9_]*)['"]?`)
+
+// the usage section of a heredoc
+var re_usage_line = regexp.MustCompile(`(?i)^\s*usage:`)
+
+// Returns the comment marker of line and the text after it, without the
+// indentation and one space, ok is false if line isn't a comment.
+func uncomment(line string) (marker, text string, ok bool) {
+	trimmed := strings.TrimLeft(line, " \t")
+	for _, marker := range Comment_markers {
+		if strings.HasPrefix(trimmed, marker) {
+			return marker, strings.TrimPrefix(trimmed[len(marker):], " "), true
+		}
+	}
+	return "", "", false
+}
+
+// The comment block starting at lines[0], ending at an empty line, a line not
+// commented with the same marker or a ---- version line.
+func comment_block(lines []string, marker string) []string {
+	block := []string{}
+	for _, line := range lines {
+		m, text, ok := uncomment(line)
+		if !ok || m != marker || strings.TrimSpace(text) == "----" {
+			break
+		}
+		block = append(block, text)
+	}
+	return block
+}
+
+// The heredoc body opened at lines[0], and the number of lines it spans,
+// terminator included. Leading tabs are removed for <<-.
+func heredoc_body(lines []string) ([]string, int) {
+	m := re_heredoc.FindStringSubmatch(lines[0])
+	if m == nil {
+		return nil, 0
+	}
+	strip_tabs, terminator := m[1] == "-", m[2]
+	body := []string{}
+	for i, line := range lines[1:] {
+		if strip_tabs {
+			line = strings.TrimLeft(line, "\t")
+		}
+		if line == terminator {
+			return body, i + 2
+		}
+		body = append(body, line)
+	}
+	// unterminated
+	return nil, len(lines)
+}
+
+// Extract_usage returns the script's help message, the first of:
+//   - a comment block starting at "Usage:", one level of comment is removed.
+//     It ends at an empty line, a line which isn't a comment or a ---- line.
+//     Comments can be indented, the markers are: # // or --
+//   - a heredoc with a "usage:" line, like: read -r -d '' usage <<EOF
+//
+// Same as docopt_get_help_string() in docopts.sh, which now calls docopts
+// extract --usage.
+func Extract_usage(script string) (string, error) {
+	lines := strings.Split(script, "\n")
+	for i := 0; i < len(lines); i++ {
+		if marker, text, ok := uncomment(lines[i]); ok && strings.HasPrefix(text, "Usage:") {
+			return strings.TrimRight(strings.Join(comment_block(lines[i:], marker), "\n"), "\n"), nil
+		}
+		if body, n := heredoc_body(lines[i:]); n > 0 {
+			for _, line := range body {
+				if re_usage_line.MatchString(line) {
+					return strings.Join(body, "\n"), nil
+				}
+			}
+			i += n - 1
+		}
+	}
+	return "", fmt.Errorf("no Usage: block found in comments or heredocs")
+}
+
+// Extract_version returns the comment blocks starting at a ---- line and
+// ending at an empty line or a line which isn't a comment, one level of
+// comment and the ---- lines are removed. Empty if there is no version. Same
+// as docopt_get_version_string() in docopts.sh.
+func Extract_version(script string) string {
+	lines := []string{}
+	marker := ""
+	for _, line := range strings.Split(script, "\n") {
+		m, text, ok := uncomment(line)
+		if marker == "" && ok && strings.HasPrefix(text, "----") {
+			marker = m
+		}
+		if marker == "" {
+			continue
+		}
+		if !ok || m != marker {
+			marker = ""
+			continue
+		}
+		if !strings.Contains(text, "----") {
+			lines = append(lines, text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func init() {
+	Register_verb(&Verb{
+		Name:    "extract",
+		Summary: "Output the help message or the version of a script.",
+		Usage: `Output the help message or the version found in a script, as docopts.sh
+needs them for the --auto mode.
+
+The help message is the first comment block starting at "Usage" followed by a
+colon, until an empty line, a line which isn't a comment or a "----" line. One
+level of comment is removed, comments can be indented and commented with #, //
+or --. A heredoc containing the usage section is also found:
+  read -r -d '' usage <<EOF
+The version is made of the comment blocks starting at "----".
+
+Usage:
+  docopts extract --usage FILE
+  docopts extract --version FILE
+
+Arguments:
+  FILE                       The script, if - is given read from standard
+                             input.
+
+Options:
+  -h, --help                 Show this help.
+  --usage                    Output the help message, an error if none.
+  --version                  Output the version message, empty if none.
+`,
+		Run: func(v *Verb, argv []string) {
+			arguments := v.Parse(argv)
+			filename := arguments["FILE"].(string)
+			var bytes []byte
+			var err error
+			if filename == "-" {
+				bytes, err = ioutil.ReadAll(os.Stdin)
+			} else {
+				bytes, err = ioutil.ReadFile(filename)
+			}
+			if err != nil {
+				docopts_error("extract: %v", err)
+			}
+
+			if arguments["--version"].(bool) {
+				if version := Extract_version(string(bytes)); version != "" {
+					fmt.Fprintln(out, version)
+				}
+				return
+			}
+			usage, err := Extract_usage(string(bytes))
+			if err != nil {
+				docopts_error("extract: %v", fmt.Errorf("%s: %v", filename, err))
+			}
+			fmt.Fprintln(out, usage)
+		},
+	})
+}
@@ -0,0 +1,64 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for extract.go
+//
+package main
+
+import (
+	"testing"
+)
+
+func TestExtract_usage(t *testing.T) {
+	tables := []struct {
+		script string
+		expect string
+	}{
+		// docopts.sh rules: first block, stops at an empty line
+		{"#!/bin/bash\n#\n# Usage: prog [-v]\n#\n# Options:\n#   -v  Verbose.\n\n# Usage: other\n",
+			"Usage: prog [-v]\n\nOptions:\n  -v  Verbose."},
+		// no space after the marker, issue #47
+		{"#Usage: prog\n#  prog -v\n", "Usage: prog\n prog -v"},
+		// stops at code or at the version
+		{"# Usage: prog\n#   prog -v\necho\n", "Usage: prog\n  prog -v"},
+		{"# Usage: prog\n# ----\n# prog 1.0\n", "Usage: prog"},
+		// indented, other markers
+		{"main() {\n    # Usage: prog\n    #   prog -v\n}\n", "Usage: prog\n  prog -v"},
+		{"// Usage: prog <x>\n//\n// Options:\n//   -v  Verbose.\n", "Usage: prog <x>\n\nOptions:\n  -v  Verbose."},
+		{"-- Usage: prog\n--   prog -v\n", "Usage: prog\n  prog -v"},
+		// heredocs
+		{"read -r -d '' usage <<EOF\nNaval Fate.\n\nUsage: prog ship\nEOF\n", "Naval Fate.\n\nUsage: prog ship"},
+		{"cat <<'EOF'\nnot a usage\nEOF\nusage=$(cat <<-\"END\"\n\tusage: prog\n\t  prog -v\n\tEND\n)\n", "usage: prog\n  prog -v"},
+		// a heredoc body isn't a comment
+		{"cat <<EOF\n# Usage: fake\nEOF\n# Usage: prog\n", "Usage: prog"},
+		// here-string, not a heredoc
+		{"grep x <<<EOF\n# Usage: prog\n", "Usage: prog"},
+	}
+	for _, table := range tables {
+		res, err := Extract_usage(table.script)
+		if err != nil || res != table.expect {
+			t.Errorf("Extract_usage(%q)\ngot: %v '%s'\nwant: '%s'", table.script, err, res, table.expect)
+		}
+	}
+
+	for _, script := range []string{"", "#!/bin/bash\n# no usage\n", "cat <<EOF\nUsage: unterminated\n", "echo Usage: prog\n"} {
+		if res, err := Extract_usage(script); err == nil {
+			t.Errorf("Extract_usage(%q) expecting error, got: '%s'", script, res)
+		}
+	}
+}
+
+func TestExtract_version(t *testing.T) {
+	tables := []struct {
+		script string
+		expect string
+	}{
+		{"#!/bin/bash\n# Usage: prog\n# ----\n# prog 1.0\n#(c) me\n\n# ----\n# extra\n", "prog 1.0\n(c) me\nextra"},
+		{"# Usage: prog\n", ""},
+		{"// ----\n// prog 2.0\ncode\n// not version\n", "prog 2.0"},
+	}
+	for _, table := range tables {
+		if res := Extract_version(table.script); res != table.expect {
+			t.Errorf("Extract_version(%q)\ngot: '%s'\nwant: '%s'", table.script, res, table.expect)
+		}
+	}
+}
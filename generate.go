@@ -0,0 +1,739 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// generate.go: compile a docopt usage into a standalone bash parser.
+//
+// The generated bash function doesn't call docopts at runtime, it sets the
+// same variables as eval "$(docopts ...)" would. See API_proposal.md.
+//
+package main
+
+import (
+	"fmt"
+	"github.com/docopt/docopts/pkg/grammar"
+	"github.com/docopt/docopts/pkg/shellout"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Marker comments around the generated parser, the code between them is
+// replaced when the parser is generated again into a script.
+const (
+	Generate_begin = "# >>> docopts generate >>>"
+	Generate_end   = "# <<< docopts generate <<<"
+)
+
+type Generator struct {
+	Doc *grammar.Doc
+	// name of the generated bash function
+	Name    string
+	Version string
+	// same behavior as docopts options
+	Options_first bool
+	No_help       bool
+	// bash 4+ associative array name, global variables are set if empty
+	Assoc string
+	// mangling of global variables names
+	Docopts *shellout.Docopts
+}
+
+// Bash outputs the parser: the function Name and its helpers prefixed by _Name_.
+func (g *Generator) Bash(w io.Writer) error {
+	if !shellout.IsBashIdentifier(g.Name) {
+		return fmt.Errorf("not a valid Bash identifier: '%s'", g.Name)
+	}
+
+	if err := g.check_annotations(); err != nil {
+		return err
+	}
+	assignments, err := g.assignments()
+	if err != nil {
+		return err
+	}
+
+	shorts := make([]string, len(g.Doc.Options))
+	longs := make([]string, len(g.Doc.Options))
+	argcounts := make([]string, len(g.Doc.Options))
+	for i, o := range g.Doc.Options {
+		shorts[i] = o.Short
+		longs[i] = o.Long
+		argcounts[i] = fmt.Sprintf("%d", o.Argcount)
+	}
+
+	prefix := "_" + g.Name + "_"
+	fmt.Fprintln(w, Generate_begin)
+	fmt.Fprintf(w, "# bash parser for %s, generated by docopts generate. Do not edit,\n", g.Doc.Prog)
+	fmt.Fprintf(w, "# call it as: %s \"$@\"\n", g.Name)
+	fmt.Fprintf(w, "%s() {\n", g.Name)
+	fmt.Fprintf(w, "    local _docopts_doc='%s'\n", shellout.Shellquote(g.Doc.Text))
+	fmt.Fprintf(w, "    local _docopts_usage='%s'\n", shellout.Shellquote(g.Doc.Usage_section))
+	fmt.Fprintf(w, "    local _docopts_version='%s'\n", shellout.Shellquote(g.Version))
+	fmt.Fprintf(w, "    local -a _docopts_shorts=( %s )\n", bash_words(shorts))
+	fmt.Fprintf(w, "    local -a _docopts_longs=( %s )\n", bash_words(longs))
+	fmt.Fprintf(w, "    local -a _docopts_argcounts=( %s )\n", strings.Join(argcounts, " "))
+	fmt.Fprint(w, "    local -a _docopts_items_opt=() _docopts_items_val=() _docopts_left=() _docopts_collected=() _docopts_v=()\n")
+	fmt.Fprint(w, "    local _docopts_i\n\n")
+
+	options_first := 0
+	if g.Options_first {
+		options_first = 1
+	}
+	fmt.Fprintf(w, "    %sparse_argv %d \"$@\"\n", prefix, options_first)
+	if !g.No_help {
+		g.bash_extras(w)
+	}
+	fmt.Fprint(w, "    _docopts_left=( \"${!_docopts_items_opt[@]}\" )\n")
+	fmt.Fprintf(w, "    if ! %sp0 || (( ${#_docopts_left[@]} )) ; then\n", prefix)
+	fmt.Fprintf(w, "        %serror 'Invalid arguments'\n", prefix)
+	fmt.Fprint(w, "    fi\n\n")
+	annotated := g.annotated()
+	// values are checked in options order, as shellout.Validate_args
+	for _, o := range g.Doc.Options {
+		if annotated[o.Name()] != nil {
+			fmt.Fprintf(w, "    %s\n", g.values_function(o.Name()))
+		}
+	}
+	for _, a := range assignments {
+		fmt.Fprintf(w, "    %s\n", a)
+	}
+	fmt.Fprint(w, "}\n")
+
+	g.bash_patterns(w, prefix)
+	g.bash_values(w, prefix)
+	fmt.Fprint(w, strings.Replace(bash_parser_runtime, "_NAME_", prefix, -1))
+	if len(annotated) > 0 {
+		fmt.Fprint(w, strings.Replace(bash_check_runtime, "_NAME_", prefix, -1))
+	}
+	fmt.Fprintln(w, Generate_end)
+	return nil
+}
+
+// --help and --version handling, docopt checks help before version
+func (g *Generator) bash_extras(w io.Writer) {
+	for _, extra := range []struct {
+		names []string
+		msg   string
+	}{
+		{[]string{"-h", "--help"}, "$_docopts_doc"},
+		{[]string{"--version"}, "$_docopts_version"},
+	} {
+		if extra.msg == "$_docopts_version" && g.Version == "" {
+			continue
+		}
+		for i, o := range g.Doc.Options {
+			for _, n := range extra.names {
+				if o.Name() != n {
+					continue
+				}
+				fmt.Fprint(w, "    for _docopts_i in \"${!_docopts_items_opt[@]}\" ; do\n")
+				fmt.Fprintf(w, "        if (( _docopts_items_opt[_docopts_i] == %d )) ; then\n", i)
+				fmt.Fprintf(w, "            printf '%%s\\n' \"%s\"\n", extra.msg)
+				fmt.Fprint(w, "            exit 0\n")
+				fmt.Fprint(w, "        fi\n")
+				fmt.Fprint(w, "    done\n")
+			}
+		}
+	}
+}
+
+// one function per pattern node, the root is p0
+func (g *Generator) bash_patterns(w io.Writer, prefix string) {
+	keys := make(map[string]int)
+	for i, k := range g.Doc.Keys() {
+		keys[k.Name] = i
+	}
+	options := make(map[*grammar.Option]int)
+	for i, o := range g.Doc.Options {
+		options[o] = i
+	}
+
+	n := 0
+	var emit func(p *grammar.Pattern) string
+	emit = func(p *grammar.Pattern) string {
+		name := fmt.Sprintf("%sp%d", prefix, n)
+		n++
+		var body string
+		switch p.Type {
+		case grammar.Argument:
+			body = fmt.Sprintf("%sleaf a %d", prefix, keys[p.Name])
+		case grammar.Command:
+			body = fmt.Sprintf("%sleaf c %d '%s'", prefix, keys[p.Name], shellout.Shellquote(p.Name))
+		case grammar.Option_leaf:
+			body = fmt.Sprintf("%sleaf o %d %d", prefix, keys[p.Name], options[p.Option])
+		default:
+			children := make([]string, len(p.Children))
+			for i, c := range p.Children {
+				children[i] = emit(c)
+			}
+			combinator := map[grammar.Pattern_type]string{
+				grammar.Required:         "required",
+				grammar.Optional:         "optional",
+				grammar.Options_shortcut: "optional",
+				grammar.One_or_more:      "one_or_more",
+				grammar.Either:           "either",
+			}[p.Type]
+			body = strings.TrimSpace(prefix + combinator + " " + strings.Join(children, " "))
+		}
+		fmt.Fprintf(w, "%s() { %s ; }\n", name, body)
+		return name
+	}
+	emit(g.Doc.Pattern)
+}
+
+// Invalid annotations, and the ones the generated parser can't check as
+// docopts does: float values are output as Go formats them, and bash matches
+// patterns as POSIX extended regexps.
+func (g *Generator) check_annotations() error {
+	for _, o := range g.Doc.Options {
+		if err := o.Check_annotations(); err != nil {
+			return err
+		}
+		if o.Type == "float" {
+			return fmt.Errorf("%s: [type: float] isn't supported by the generated parser, use docopts parse", o.Name())
+		}
+		if o.Pattern != "" {
+			if _, err := regexp.CompilePOSIX(o.Pattern); err != nil {
+				return fmt.Errorf("%s: [pattern: %s] must be a POSIX extended regexp for the generated parser: %v",
+					o.Name(), o.Pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// The options of the keys with [env: NAME] or a value annotation, by key.
+func (g *Generator) annotated() map[string]*grammar.Option {
+	annotated := make(map[string]*grammar.Option)
+	for _, k := range g.Doc.Keys() {
+		if o := k.Option; o != nil && o.Argcount > 0 && (o.Env != "" || o.Has_annotations()) {
+			annotated[k.Name] = o
+		}
+	}
+	return annotated
+}
+
+// name of the function setting _docopts_v for an annotated key
+func (g *Generator) values_function(key string) string {
+	for i, k := range g.Doc.Keys() {
+		if k.Name == key {
+			return fmt.Sprintf("_%s_v%d", g.Name, i)
+		}
+	}
+	return ""
+}
+
+// One function per annotated key setting _docopts_v: the matched values, or
+// the [env: NAME] variable, or the default, then checked as
+// shellout.Validate_args does.
+func (g *Generator) bash_values(w io.Writer, prefix string) {
+	annotated := g.annotated()
+	repeating := g.Doc.Repeating()
+	for i, k := range g.Doc.Keys() {
+		o := annotated[k.Name]
+		if o == nil {
+			continue
+		}
+		value := g.Doc.Default_value(k, repeating)
+		_, is_list := value.([]string)
+		var fallback string
+		switch {
+		case is_list:
+			fallback = fmt.Sprintf("_docopts_v=%s", shellout.To_bash(value))
+		case value != nil:
+			fallback = fmt.Sprintf("_docopts_v=( %s )", shellout.To_bash(value))
+		}
+
+		fmt.Fprintf(w, "%s() {\n", g.values_function(k.Name))
+		fmt.Fprintf(w, "    %sget %d\n", prefix, i)
+		if o.Env != "" {
+			fmt.Fprint(w, "    if (( ${#_docopts_v[@]} == 0 )) ; then\n")
+			fmt.Fprintf(w, "        if [[ -n ${%s+x} ]] ; then\n", o.Env)
+			if is_list {
+				// split on whitespace, as strings.Fields()
+				fmt.Fprintf(w, "            IFS=$' \\t\\n' read -r -d '' -a _docopts_v <<< \"$%s\" || :\n", o.Env)
+			} else {
+				fmt.Fprintf(w, "            _docopts_v=( \"$%s\" )\n", o.Env)
+			}
+			if fallback != "" {
+				fmt.Fprint(w, "        else\n")
+				fmt.Fprintf(w, "            %s\n", fallback)
+			}
+			fmt.Fprint(w, "        fi\n")
+			fmt.Fprint(w, "    fi\n")
+		} else if fallback != "" {
+			fmt.Fprintf(w, "    (( ${#_docopts_v[@]} )) || %s\n", fallback)
+		}
+		if o.Has_annotations() {
+			exists, convert := 0, 0
+			if o.Exists {
+				exists = 1
+			}
+			// repeatable values are checked but kept as strings
+			if !is_list {
+				convert = 1
+			}
+			choices := ""
+			if o.Choices != nil {
+				choices = " " + bash_words(o.Choices)
+			}
+			fmt.Fprintf(w, "    %scheck '%s' '%s' %d %d '%s'%s\n", prefix, shellout.Shellquote(o.Name()),
+				shellout.Shellquote(o.Type), exists, convert, shellout.Shellquote(o.Pattern), choices)
+		}
+		fmt.Fprint(w, "}\n")
+	}
+}
+
+// bash statements setting the parsed values, same names and values as
+// Print_bash_global or Print_bash_args
+func (g *Generator) assignments() ([]string, error) {
+	repeating := g.Doc.Repeating()
+	keys := g.Doc.Keys()
+	index := make(map[string]int)
+	for i, k := range keys {
+		index[k.Name] = i
+	}
+	// same order as Sort_args_keys
+	sorted := make([]string, len(keys))
+	for i, k := range keys {
+		sorted[i] = k.Name
+	}
+	sort.Strings(sorted)
+
+	statements := []string{}
+	if g.Assoc != "" {
+		if !shellout.IsBashIdentifier(g.Assoc) {
+			return nil, fmt.Errorf("-A: not a valid Bash identifier: '%s'", g.Assoc)
+		}
+		if g.Docopts.Output_declare {
+			statements = append(statements, fmt.Sprintf("declare -gA %s", g.Assoc))
+		}
+	}
+
+	annotated := g.annotated()
+	varmap := make(map[string]string)
+	for _, key := range sorted {
+		leaf := keys[index[key]]
+		value := g.Doc.Default_value(leaf, repeating)
+		// statements setting _docopts_v, with the default list
+		get := []string{fmt.Sprintf("_%s_get %d", g.Name, index[key])}
+		if annotated[key] != nil {
+			// the values function also sets the default
+			get = []string{g.values_function(key)}
+		} else if _, is_list := value.([]string); is_list {
+			get = append(get, fmt.Sprintf("(( ${#_docopts_v[@]} )) || _docopts_v=%s", shellout.To_bash(value)))
+		}
+
+		if g.Assoc != "" {
+			lhs := fmt.Sprintf("%s['%s']", g.Assoc, shellout.Shellquote(key))
+			switch value.(type) {
+			case []string:
+				statements = append(statements, get...)
+				statements = append(statements,
+					fmt.Sprintf("for _docopts_i in \"${!_docopts_v[@]}\" ; do %s['%s,'$_docopts_i]=${_docopts_v[_docopts_i]} ; done",
+						g.Assoc, shellout.Shellquote(key)),
+					fmt.Sprintf("%s['%s,#']=${#_docopts_v[@]}", g.Assoc, shellout.Shellquote(key)))
+			default:
+				statements = append(statements, get...)
+				statements = append(statements, scalar_assignment(lhs, value))
+			}
+			continue
+		}
+
+		if key == "--" && g.Docopts.Global_prefix == "" {
+			// skipped as in Print_bash_global
+			continue
+		}
+		name, err := g.Docopts.Name_mangle(key)
+		if err != nil {
+			return nil, err
+		}
+		if prev_key, seen := varmap[name]; seen {
+			return nil, fmt.Errorf("%s: two or more elements have identically mangled names", prev_key)
+		}
+		varmap[name] = key
+
+		switch value.(type) {
+		case []string:
+			statements = append(statements, get...)
+			statements = append(statements, fmt.Sprintf("%s=( ${_docopts_v[@]+\"${_docopts_v[@]}\"} )", name))
+		default:
+			statements = append(statements, get...)
+			statements = append(statements, scalar_assignment(name, value))
+		}
+	}
+	return statements, nil
+}
+
+// assignment of a non list value from $_docopts_v, value is the default
+func scalar_assignment(lhs string, value interface{}) string {
+	switch value.(type) {
+	case bool:
+		return fmt.Sprintf("if (( ${#_docopts_v[@]} )) ; then %s=true ; else %s=false ; fi", lhs, lhs)
+	case int:
+		return fmt.Sprintf("%s=${#_docopts_v[@]}", lhs)
+	}
+	// string or nil
+	return fmt.Sprintf("if (( ${#_docopts_v[@]} )) ; then %s=${_docopts_v[0]} ; else %s=%s ; fi", lhs, lhs, shellout.To_bash(value))
+}
+
+// Runtime of the generated parser, _NAME_ is replaced by the functions prefix.
+// Parsed argv items are stored in _docopts_items_opt (option index or -1 for a
+// positional argument) and _docopts_items_val. Matching follows docopt: the
+// indexes of the items not matched yet are in _docopts_left and matched leaves
+// are appended to _docopts_collected as key_index:item_index.
+const bash_parser_runtime = `_NAME_error() {
+    printf 'error: %s\n%s\n' "$1" "$_docopts_usage" >&2
+    exit 64
+}
+_NAME_add() {
+    _docopts_items_opt+=( "$1" )
+    _docopts_items_val+=( "$2" )
+}
+_NAME_parse_argv() {
+    local options_first=$1 tok long o i rest
+    local -a similar
+    shift
+    while (( $# )) ; do
+        tok=$1
+        shift
+        if [[ $tok == -- ]] || { [[ $tok != -* || $tok == - ]] && (( options_first )) ; } ; then
+            _NAME_add -1 "$tok"
+            while (( $# )) ; do
+                _NAME_add -1 "$1"
+                shift
+            done
+        elif [[ $tok == --* ]] ; then
+            long=${tok%%=*}
+            similar=()
+            for i in "${!_docopts_longs[@]}" ; do
+                if [[ ${_docopts_longs[i]} == "$long" ]] ; then
+                    similar+=( "$i" )
+                fi
+            done
+            if (( ${#similar[@]} == 0 )) ; then
+                for i in "${!_docopts_longs[@]}" ; do
+                    if [[ -n ${_docopts_longs[i]} && ${_docopts_longs[i]} == "$long"* ]] ; then
+                        similar+=( "$i" )
+                    fi
+                done
+            fi
+            if (( ${#similar[@]} > 1 )) ; then
+                rest=
+                for i in "${similar[@]}" ; do
+                    rest+="${rest:+, }${_docopts_longs[i]}"
+                done
+                _NAME_error "Ambiguous option '$long', it could be: $rest"
+            elif (( ${#similar[@]} == 0 )) ; then
+                _NAME_error "Invalid option '$long'"
+            fi
+            o=${similar[0]}
+            if (( _docopts_argcounts[o] == 0 )) ; then
+                if [[ $tok == *=* ]] ; then
+                    _NAME_error "${_docopts_longs[o]} doesn't take a value"
+                fi
+                _NAME_add "$o" ''
+            elif [[ $tok == *=* ]] ; then
+                _NAME_add "$o" "${tok#*=}"
+            elif (( $# == 0 )) || [[ $1 == -- ]] ; then
+                _NAME_error "${_docopts_longs[o]} requires a value"
+            else
+                _NAME_add "$o" "$1"
+                shift
+            fi
+        elif [[ $tok == -* && $tok != - ]] ; then
+            rest=${tok#-}
+            while [[ -n $rest ]] ; do
+                long=-${rest:0:1}
+                rest=${rest:1}
+                o=-1
+                for i in "${!_docopts_shorts[@]}" ; do
+                    if [[ ${_docopts_shorts[i]} == "$long" ]] ; then
+                        o=$i
+                        break
+                    fi
+                done
+                if (( o < 0 )) ; then
+                    _NAME_error "Invalid option '$long'"
+                fi
+                if (( _docopts_argcounts[o] == 0 )) ; then
+                    _NAME_add "$o" ''
+                elif [[ -n $rest ]] ; then
+                    _NAME_add "$o" "$rest"
+                    rest=
+                elif (( $# == 0 )) || [[ $1 == -- ]] ; then
+                    _NAME_error "$long requires a value"
+                else
+                    _NAME_add "$o" "$1"
+                    shift
+                fi
+            done
+        else
+            _NAME_add -1 "$tok"
+        fi
+    done
+    return 0
+}
+# $1: a for argument, c for command or o for option, $2: key index,
+# $3: command name or option index
+_NAME_leaf() {
+    local n i
+    for n in "${!_docopts_left[@]}" ; do
+        i=${_docopts_left[n]}
+        case $1 in
+            o)
+                [[ ${_docopts_items_opt[i]} == "$3" ]] || continue
+                ;;
+            a)
+                [[ ${_docopts_items_opt[i]} == -1 ]] || continue
+                ;;
+            c)
+                [[ ${_docopts_items_opt[i]} == -1 ]] || continue
+                # a command must be the first positional argument
+                [[ ${_docopts_items_val[i]} == "$3" ]] || return 1
+                ;;
+        esac
+        _docopts_left=( "${_docopts_left[@]:0:n}" "${_docopts_left[@]:n+1}" )
+        _docopts_collected+=( "$2:$i" )
+        return 0
+    done
+    return 1
+}
+_NAME_required() {
+    local -a left=( ${_docopts_left[@]+"${_docopts_left[@]}"} )
+    local -a collected=( ${_docopts_collected[@]+"${_docopts_collected[@]}"} )
+    local f
+    for f in "$@" ; do
+        if ! "$f" ; then
+            _docopts_left=( ${left[@]+"${left[@]}"} )
+            _docopts_collected=( ${collected[@]+"${collected[@]}"} )
+            return 1
+        fi
+    done
+    return 0
+}
+_NAME_optional() {
+    local f
+    for f in "$@" ; do
+        "$f" || :
+    done
+    return 0
+}
+_NAME_one_or_more() {
+    local -a left=( ${_docopts_left[@]+"${_docopts_left[@]}"} )
+    local -a collected=( ${_docopts_collected[@]+"${_docopts_collected[@]}"} )
+    local times=0 previous
+    while : ; do
+        previous=${#_docopts_left[@]}
+        "$1" || break
+        times=$((times + 1))
+        (( previous != ${#_docopts_left[@]} )) || break
+    done
+    if (( times == 0 )) ; then
+        _docopts_left=( ${left[@]+"${left[@]}"} )
+        _docopts_collected=( ${collected[@]+"${collected[@]}"} )
+        return 1
+    fi
+    return 0
+}
+# the alternative leaving the fewest items wins, the first one on tie
+_NAME_either() {
+    local -a left=( ${_docopts_left[@]+"${_docopts_left[@]}"} )
+    local -a collected=( ${_docopts_collected[@]+"${_docopts_collected[@]}"} )
+    local -a best_left=() best_collected=()
+    local f found=false
+    for f in "$@" ; do
+        _docopts_left=( ${left[@]+"${left[@]}"} )
+        _docopts_collected=( ${collected[@]+"${collected[@]}"} )
+        "$f" || continue
+        if ! $found || (( ${#_docopts_left[@]} < ${#best_left[@]} )) ; then
+            found=true
+            best_left=( ${_docopts_left[@]+"${_docopts_left[@]}"} )
+            best_collected=( ${_docopts_collected[@]+"${_docopts_collected[@]}"} )
+        fi
+    done
+    if $found ; then
+        _docopts_left=( ${best_left[@]+"${best_left[@]}"} )
+        _docopts_collected=( ${best_collected[@]+"${best_collected[@]}"} )
+        return 0
+    fi
+    _docopts_left=( ${left[@]+"${left[@]}"} )
+    _docopts_collected=( ${collected[@]+"${collected[@]}"} )
+    return 1
+}
+# $1: key index, matched values are set in _docopts_v
+_NAME_get() {
+    local c
+    _docopts_v=()
+    for c in ${_docopts_collected[@]+"${_docopts_collected[@]}"} ; do
+        if [[ ${c%%:*} == "$1" ]] ; then
+            _docopts_v+=( "${_docopts_items_val[${c#*:}]}" )
+        fi
+    done
+    return 0
+}
+`
+
+// Checks of the values annotations, only output if the usage has some. The
+// errors are the same as grammar.Option.Convert() errors.
+const bash_check_runtime = `# $1: option name, $2: type, $3: 1 if the path must exist, $4: 1 to convert
+# an int, $5: pattern, then the choices. The values are in _docopts_v.
+_NAME_check() {
+    local name=$1 type=$2 exists=$3 convert=$4 pattern=$5 v c found i
+    local re_int='^([+-]?)([0-9]+)$'
+    shift 5
+    for i in "${!_docopts_v[@]}" ; do
+        v=${_docopts_v[i]}
+        if (( $# )) ; then
+            found=false
+            for c in "$@" ; do
+                [[ $v != "$c" ]] || found=true
+            done
+            $found || _NAME_error "$name must be one of: $*, got '$v'"
+        fi
+        if [[ -n $pattern && ! $v =~ $pattern ]] ; then
+            _NAME_error "$name must match $pattern, got '$v'"
+        fi
+        case $type in
+            int)
+                [[ $v =~ $re_int ]] || _NAME_error "$name must be an int, got '$v'"
+                if (( convert )) ; then
+                    # as strconv.Atoi: 010 is 10
+                    _docopts_v[i]=$(( ${BASH_REMATCH[1]}10#${BASH_REMATCH[2]} ))
+                fi
+                ;;
+            path)
+                if (( exists )) && [[ ! -e $v ]] ; then
+                    _NAME_error "$name must be an existing path, got '$v'"
+                fi
+                ;;
+        esac
+    done
+    return 0
+}
+`
+
+// Splice replaces the generated parser between the marker comments in script.
+func Splice(script, code string) (string, error) {
+	begin := strings.Index(script, Generate_begin+"\n")
+	end := strings.Index(script, Generate_end+"\n")
+	if begin < 0 || end < begin {
+		return "", fmt.Errorf("marker comments not found, the parser is written between:\n%s\n%s",
+			Generate_begin, Generate_end)
+	}
+	return script[:begin] + code + script[end+len(Generate_end)+1:], nil
+}
+
+func init() {
+	Register_verb(&Verb{
+		Name:    "generate",
+		Summary: "Generate a standalone bash parser for USAGE.",
+		Usage: `Generate a standalone bash parser for USAGE.
+
+The generated bash function parses its arguments and sets the same variables
+as eval "$(docopts ...)", docopts is not needed at runtime. Bash 4+ is
+required with -A. Options annotations are checked, [env: NAME] is read, but
+[type: float] isn't supported and [pattern: ...] must be a POSIX extended
+regexp, matched by bash.
+
+Usage:
+  docopts generate [options] USAGE
+  docopts generate [options] -f FILENAME
+
+Arguments:
+  USAGE                      The help message in docopt format.
+                             If - is given, read it from standard input.
+
+Options:
+  -h, --help                 Show this help.
+  -f, --file=FILENAME        Read the usage from the comment header of the
+                             script FILENAME, as docopts.sh does, and write
+                             the parser in FILENAME between the marker
+                             comments:
+                               # >>> docopts generate >>>
+                               # <<< docopts generate <<<
+  -n, --name=<name>          Name of the generated bash function
+                             [default: docopts_parse].
+  -V, --version=<msg>        Version message displayed on --version.
+                             With -f, the version found in FILENAME, see:
+                             docopts extract --help.
+  -O, --options-first        Disallow interspersing options and positional
+                             arguments.
+  -H, --no-help              Don't handle --help and --version.
+  -A <name>                  Set the parsed values in the bash 4+ associative
+                             array <name>, instead of global variables.
+  -G <prefix>                Prefix global variables names with <prefix>_.
+  --no-declare               Don't declare the associative array with -A.
+
+Examples:
+  docopts generate -n parse "$usage" > parser.sh
+  docopts generate -A args -f myscript.sh
+`,
+		Run: func(v *Verb, argv []string) {
+			arguments := v.Parse(argv)
+			filename, _ := arguments.String("--file")
+			var doc, script string
+			if filename != "" {
+				bytes, err := ioutil.ReadFile(filename)
+				if err != nil {
+					docopts_error("generate: %v", err)
+				}
+				script = string(bytes)
+				doc, err = Extract_usage(script)
+				if err != nil {
+					docopts_error("generate: %v", err)
+				}
+			} else {
+				doc, _ = arguments.String("USAGE")
+				if doc == "-" {
+					bytes, _ := ioutil.ReadAll(os.Stdin)
+					doc = string(bytes)
+				}
+			}
+
+			parsed, err := grammar.Parse_doc(strings.TrimSpace(doc))
+			if err != nil {
+				docopts_error("generate: USAGE: %v", err)
+			}
+			g := &Generator{
+				Doc:           parsed,
+				Options_first: arguments["--options-first"].(bool),
+				No_help:       arguments["--no-help"].(bool),
+				Docopts: &shellout.Docopts{
+					Mangle_key:     true,
+					Output_declare: !arguments["--no-declare"].(bool),
+				},
+			}
+			g.Name, _ = arguments.String("--name")
+			g.Version, _ = arguments.String("--version")
+			if g.Version == "" && filename != "" {
+				// as docopts run does
+				g.Version = Extract_version(script)
+			}
+			g.Assoc, _ = arguments.String("-A")
+			g.Docopts.Global_prefix, _ = arguments.String("-G")
+
+			if filename == "" {
+				err = g.Bash(out)
+				if err != nil {
+					docopts_error("generate: %v", err)
+				}
+				return
+			}
+
+			code := &strings.Builder{}
+			err = g.Bash(code)
+			if err == nil {
+				script, err = Splice(script, code.String())
+			}
+			if err == nil {
+				err = ioutil.WriteFile(filename, []byte(script), 0)
+			}
+			if err != nil {
+				docopts_error("generate: %v", fmt.Errorf("%s: %v", filename, err))
+			}
+		},
+	})
+}
@@ -0,0 +1,142 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for generate.go
+//
+package main
+
+import (
+	"bytes"
+	"github.com/docopt/docopts/pkg/grammar"
+	"github.com/docopt/docopts/pkg/shellout"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerator_assignments(t *testing.T) {
+	doc, err := grammar.Parse_doc("Usage: prog [-v...] [--speed=<kn>] [--] <file>...\n\nOptions:\n  --speed=<kn>  Speed [default: 10].")
+	if err != nil {
+		t.Fatalf("Parse_doc error: %v", err)
+	}
+
+	g := &Generator{Doc: doc, Name: "parse", Docopts: &shellout.Docopts{Mangle_key: true, Output_declare: true}}
+	res, err := g.assignments()
+	if err != nil {
+		t.Fatalf("assignments error: %v", err)
+	}
+	// '--' is skipped for globals, keys are: --speed, -v, <file>
+	expect := []string{
+		"_parse_get 1",
+		"if (( ${#_docopts_v[@]} )) ; then speed=${_docopts_v[0]} ; else speed='10' ; fi",
+		"_parse_get 0",
+		"v=${#_docopts_v[@]}",
+		"_parse_get 3",
+		"(( ${#_docopts_v[@]} )) || _docopts_v=()",
+		`file=( ${_docopts_v[@]+"${_docopts_v[@]}"} )`,
+	}
+	if !reflect.DeepEqual(res, expect) {
+		t.Errorf("assignments\ngot: %#v\nwant: %#v", res, expect)
+	}
+
+	g.Assoc = "args"
+	res, _ = g.assignments()
+	expect_double_dash := "if (( ${#_docopts_v[@]} )) ; then args['--']=true ; else args['--']=false ; fi"
+	if res[0] != "declare -gA args" || res[2] != expect_double_dash {
+		t.Errorf("assignments -A got: %#v", res)
+	}
+	if res[len(res)-1] != "args['<file>,#']=${#_docopts_v[@]}" {
+		t.Errorf("assignments -A list got: %#v", res[len(res)-1])
+	}
+
+	g.Assoc = ""
+	g.Docopts.Global_prefix = "1"
+	if _, err = g.assignments(); err == nil {
+		t.Errorf("assignments expecting mangling error")
+	}
+}
+
+func TestGenerator_Bash(t *testing.T) {
+	doc, _ := grammar.Parse_doc("Usage: prog ship <name>\n\nOptions:\n  -h --help  Help.")
+	g := &Generator{Doc: doc, Name: "parse", Docopts: &shellout.Docopts{Mangle_key: true}}
+	var buf bytes.Buffer
+	if err := g.Bash(&buf); err != nil {
+		t.Fatalf("Bash error: %v", err)
+	}
+	res := buf.String()
+	for _, expect := range []string{
+		Generate_begin + "\n",
+		"parse() {\n",
+		"    local _docopts_usage='Usage: prog ship <name>'\n",
+		"_parse_p0() { _parse_required _parse_p1 ; }\n",
+		"_parse_p3() { _parse_leaf a 1 ; }\n",
+		"_parse_either() {\n",
+		// --help is handled
+		"        if (( _docopts_items_opt[_docopts_i] == 0 )) ; then\n",
+		Generate_end + "\n",
+	} {
+		if !strings.Contains(res, expect) {
+			t.Errorf("Bash output doesn't contain: '%s'\n%s", expect, res)
+		}
+	}
+	if strings.Contains(res, "_NAME_") {
+		t.Errorf("Bash output contains _NAME_ placeholder")
+	}
+
+	g.Name = "not-valid"
+	if err := g.Bash(&buf); err == nil {
+		t.Errorf("Bash expecting error for function name: '%s'", g.Name)
+	}
+}
+
+func TestGenerator_Bash_annotations(t *testing.T) {
+	doc, _ := grammar.Parse_doc(`Usage: prog [--speed=<kn>] [-n <n>]...
+
+Options:
+  --speed=<kn>  Speed [type: int] [env: SPEED] [default: 10].
+  -n <n>        Numbers [choices: 1 2].`)
+	g := &Generator{Doc: doc, Name: "parse", Docopts: &shellout.Docopts{Mangle_key: true}}
+	var buf bytes.Buffer
+	if err := g.Bash(&buf); err != nil {
+		t.Fatalf("Bash error: %v", err)
+	}
+	res := buf.String()
+	for _, expect := range []string{
+		// checked in options order before the assignments
+		"    _parse_v0\n    _parse_v1\n    _parse_v0\n",
+		"        if [[ -n ${SPEED+x} ]] ; then\n            _docopts_v=( \"$SPEED\" )\n        else\n            _docopts_v=( '10' )\n",
+		"    _parse_check '--speed' 'int' 0 1 ''\n",
+		"    _parse_check '-n' '' 0 0 '' '1' '2'\n",
+		"_parse_check() {\n",
+	} {
+		if !strings.Contains(res, expect) {
+			t.Errorf("Bash output doesn't contain: '%s'\n%s", expect, res)
+		}
+	}
+
+	for _, options := range []string{
+		"--speed=<kn>  Speed [type: float].",
+		"--speed=<kn>  Speed [pattern: \\d+].",
+		"--speed=<kn>  Speed [type: integer].",
+	} {
+		doc, _ = grammar.Parse_doc("Usage: prog [--speed=<kn>]\n\nOptions:\n  " + options)
+		g.Doc = doc
+		if err := g.Bash(&buf); err == nil {
+			t.Errorf("Bash expecting error for: '%s'", options)
+		}
+	}
+}
+
+func TestSplice(t *testing.T) {
+	script := "#!/bin/bash\n" + Generate_begin + "\nold\n" + Generate_end + "\nparse \"$@\"\n"
+	code := Generate_begin + "\nnew\n" + Generate_end + "\n"
+	res, err := Splice(script, code)
+	expect := "#!/bin/bash\n" + code + "parse \"$@\"\n"
+	if err != nil || res != expect {
+		t.Errorf("Splice\ngot: '%s' %v\nwant: '%s'", res, err, expect)
+	}
+
+	if _, err = Splice("#!/bin/bash\n", code); err == nil {
+		t.Errorf("Splice expecting error without markers")
+	}
+}
+
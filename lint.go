@@ -0,0 +1,445 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// lint.go: docopts lint, static checks of a usage, reporting the problems
+// which otherwise only surface at runtime, when an argv hits them.
+//
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/docopt/docopts/pkg/grammar"
+	"github.com/docopt/docopts/pkg/shellout"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A problem found in a usage: the check name, the offending key or pattern
+// and a message.
+type Lint_issue struct {
+	Check   string `json:"check"`
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// Names of the lint checks, in report order.
+var Lint_checks = []string{
+	"syntax",
+	"duplicate-option",
+	"duplicate-default",
+	"undescribed-option",
+	"unused-option",
+	"unmangleable",
+	"collision",
+	"ambiguous-pattern",
+}
+
+var re_lint_default = regexp.MustCompile(`(?i)\[default:`)
+
+// Lint checks the usage doc, the keys are mangled as -G prefix would.
+func Lint(doc, prefix string) []*Lint_issue {
+	issues := lint_options(grammar.Parse_options(doc))
+
+	parsed, err := grammar.Parse_doc(doc)
+	if err != nil {
+		return append([]*Lint_issue{{"syntax", "", err.Error()}}, issues...)
+	}
+	for _, o := range parsed.Options {
+		if err := o.Check_annotations(); err != nil {
+			issues = append(issues, &Lint_issue{"syntax", o.Name(), err.Error()})
+		}
+	}
+	issues = append(issues, lint_pattern_options(parsed)...)
+	issues = append(issues, lint_mangle(parsed, prefix)...)
+	patterns := parsed.Usage_patterns()
+	issues = append(issues, lint_alternatives(patterns, parsed.Usage_lines)...)
+	for _, p := range patterns {
+		issues = append(issues, lint_ambiguous(p)...)
+	}
+	return issues
+}
+
+// options described twice, or with more than one default
+func lint_options(options []*grammar.Option) []*Lint_issue {
+	issues := []*Lint_issue{}
+	seen := make(map[string]bool)
+	for _, o := range options {
+		for _, name := range o.Names() {
+			if seen[name] {
+				issues = append(issues, &Lint_issue{"duplicate-option", name,
+					fmt.Sprintf("%s is described more than once in the Options: section", name)})
+			}
+			seen[name] = true
+		}
+	}
+	for _, o := range options {
+		if n := len(re_lint_default.FindAllString(o.Description, -1)); n > 1 {
+			issues = append(issues, &Lint_issue{"duplicate-default", o.Name(),
+				fmt.Sprintf("%s has %d [default: ...], only one is used", o.Name(), n)})
+		}
+	}
+	return issues
+}
+
+// options used in patterns without description, described options not used
+// by any pattern nor [options]
+func lint_pattern_options(d *grammar.Doc) []*Lint_issue {
+	issues := []*Lint_issue{}
+	used := make(map[string]bool)
+	for _, l := range d.Pattern.Leaves() {
+		if l.Type == grammar.Option_leaf {
+			used[l.Name] = true
+		}
+	}
+	for _, o := range d.Options {
+		if !o.Described {
+			issues = append(issues, &Lint_issue{"undescribed-option", o.Name(),
+				fmt.Sprintf("%s is used in a usage pattern but not described in an Options: section", o.Name())})
+		}
+	}
+	for _, o := range d.Options {
+		if o.Described && !used[o.Name()] {
+			issues = append(issues, &Lint_issue{"unused-option", o.Name(),
+				fmt.Sprintf("%s is described but not used in any usage pattern, add [options]?", o.Name())})
+		}
+	}
+	return issues
+}
+
+// keys which can't be variable names, and keys mangled to the same name
+func lint_mangle(d *grammar.Doc, prefix string) []*Lint_issue {
+	issues := []*Lint_issue{}
+	docopts := &shellout.Docopts{Mangle_key: true, Global_prefix: prefix}
+	// mangled name => key
+	seen := make(map[string]string)
+	for _, l := range d.Keys() {
+		if l.Name == "--" && prefix == "" {
+			// skipped by the output, see Name_mangler
+			continue
+		}
+		name, err := docopts.Name_mangle(l.Name)
+		if err != nil {
+			issues = append(issues, &Lint_issue{"unmangleable", l.Name, err.Error()})
+			continue
+		}
+		if prev_key, found := seen[name]; found {
+			issues = append(issues, &Lint_issue{"collision", l.Name,
+				fmt.Sprintf("%s and %s are both mangled to '%s'", prev_key, l.Name, name)})
+			continue
+		}
+		seen[name] = l.Name
+	}
+	return issues
+}
+
+// Alternatives accepting the arguments of a later one: the first one always
+// matches, the next ones are never used. names are the alternatives as
+// displayed. Synonyms of one option, like -h | --help, aren't ambiguous.
+func lint_alternatives(alternatives []*grammar.Pattern, names []string) []*Lint_issue {
+	issues := []*Lint_issue{}
+	if lint_synonyms(alternatives) {
+		return issues
+	}
+	for i, c := range alternatives {
+		for first := 0; first < i; first++ {
+			if lint_covers(alternatives[first], c) {
+				issues = append(issues, &Lint_issue{"ambiguous-pattern", names[i],
+					fmt.Sprintf("'%s' is never matched, '%s' matches the same arguments first", names[i], names[first])})
+				break
+			}
+		}
+	}
+	return issues
+}
+
+// true if p accepts all the arguments accepted by later, compared on their
+// expansions, or on their shapes if they can't be expanded
+func lint_covers(p, later *grammar.Pattern) bool {
+	accepted, ok := lint_expand(p)
+	expansions, later_ok := lint_expand(later)
+	if !ok || !later_ok {
+		return lint_shape(p) == lint_shape(later)
+	}
+	for _, e := range expansions {
+		found := false
+		for _, a := range accepted {
+			found = found || a.accepts(e)
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// One way of matching a pattern: positional elements in order, commands
+// verbatim and arguments as <>, and options sorted, their order doesn't
+// matter.
+type lint_expansion struct {
+	positional []string
+	options    []string
+}
+
+// an argument also accepts a command, but --
+func (e *lint_expansion) accepts(other *lint_expansion) bool {
+	if len(e.positional) != len(other.positional) || len(e.options) != len(other.options) {
+		return false
+	}
+	for i, elem := range e.positional {
+		o := other.positional[i]
+		command := o != "<>" && o != "--" && !strings.Contains(o, "(")
+		if elem != o && !(elem == "<>" && command) {
+			return false
+		}
+	}
+	for i, o := range e.options {
+		if o != other.options[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *lint_expansion) join(other *lint_expansion) *lint_expansion {
+	joined := &lint_expansion{
+		positional: append(append([]string{}, e.positional...), other.positional...),
+		options:    append(append([]string{}, e.options...), other.options...),
+	}
+	sort.Strings(joined.options)
+	return joined
+}
+
+// All the ways of matching p, an optional element is present or not. Repeated
+// elements are kept as one element. false if p can't be expanded: [options]
+// or too many expansions.
+func lint_expand(p *grammar.Pattern) ([]*lint_expansion, bool) {
+	switch p.Type {
+	case grammar.Options_shortcut:
+		return nil, false
+	case grammar.Argument, grammar.Command:
+		return []*lint_expansion{{positional: []string{lint_shape(p)}}}, true
+	case grammar.Option_leaf:
+		return []*lint_expansion{{options: []string{lint_shape(p)}}}, true
+	case grammar.One_or_more:
+		options := true
+		for _, l := range p.Leaves() {
+			options = options && l.Type == grammar.Option_leaf
+		}
+		if options {
+			return []*lint_expansion{{options: []string{lint_shape(p)}}}, true
+		}
+		return []*lint_expansion{{positional: []string{lint_shape(p)}}}, true
+	case grammar.Either:
+		result := []*lint_expansion{}
+		for _, c := range p.Children {
+			expansions, ok := lint_expand(c)
+			if !ok {
+				return nil, false
+			}
+			result = append(result, expansions...)
+		}
+		return result, len(result) <= max_sequences
+	}
+	// Required, Optional
+	result := []*lint_expansion{{}}
+	for _, c := range p.Children {
+		expansions, ok := lint_expand(c)
+		if !ok {
+			return nil, false
+		}
+		if p.Type == grammar.Optional {
+			expansions = append(expansions, &lint_expansion{})
+		}
+		product := []*lint_expansion{}
+		for _, prefix := range result {
+			for _, e := range expansions {
+				product = append(product, prefix.join(e))
+			}
+		}
+		if len(product) > max_sequences {
+			return nil, false
+		}
+		result = product
+	}
+	return result, true
+}
+
+// true if all leaves of the alternatives are the same option
+func lint_synonyms(alternatives []*grammar.Pattern) bool {
+	identity := ""
+	for _, c := range alternatives {
+		for _, l := range c.Leaves() {
+			if l.Type != grammar.Option_leaf {
+				return false
+			}
+			if identity == "" {
+				identity = lint_option_identity(l.Option)
+			} else if lint_option_identity(l.Option) != identity {
+				return false
+			}
+		}
+	}
+	return identity != ""
+}
+
+// an option is the same whichever of its names is written
+func lint_option_identity(o *grammar.Option) string {
+	return o.Short + "|" + o.Long
+}
+
+// lint_alternatives() of all Either in p
+func lint_ambiguous(p *grammar.Pattern) []*Lint_issue {
+	issues := []*Lint_issue{}
+	if p.Type == grammar.Either {
+		names := make([]string, len(p.Children))
+		for i, c := range p.Children {
+			names[i] = c.String()
+		}
+		issues = append(issues, lint_alternatives(p.Children, names)...)
+	}
+	for _, c := range p.Children {
+		issues = append(issues, lint_ambiguous(c)...)
+	}
+	return issues
+}
+
+// the pattern in docopt syntax, positional argument names replaced by <>
+// and options by their identity
+func lint_shape(p *grammar.Pattern) string {
+	switch p.Type {
+	case grammar.Argument:
+		return "<>"
+	case grammar.Option_leaf:
+		return "option(" + lint_option_identity(p.Option) + ")"
+	}
+	if p.Is_leaf() || p.Type == grammar.Options_shortcut {
+		return p.String()
+	}
+	children := make([]string, len(p.Children))
+	for i, c := range p.Children {
+		children[i] = lint_shape(c)
+	}
+	return fmt.Sprintf("%s(%s)", p.Type, strings.Join(children, " "))
+}
+
+// Remove the issues of the ignored checks, unknown check names are an error.
+func Lint_filter(issues []*Lint_issue, ignore []string) ([]*Lint_issue, error) {
+	ignored := make(map[string]bool)
+	for _, check := range ignore {
+		known := false
+		for _, c := range Lint_checks {
+			known = known || c == check
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown check '%s', known checks: %s", check, strings.Join(Lint_checks, " "))
+		}
+		ignored[check] = true
+	}
+	kept := []*Lint_issue{}
+	for _, issue := range issues {
+		if !ignored[issue.Check] {
+			kept = append(kept, issue)
+		}
+	}
+	return kept, nil
+}
+
+// Print_lint outputs one line per issue prefixed by source, or a JSON array.
+func Print_lint(w io.Writer, source string, issues []*Lint_issue, as_json bool) error {
+	if as_json {
+		enc := json.NewEncoder(w)
+		// keep '<argument>' keys readable, as write_json()
+		enc.SetEscapeHTML(false)
+		return enc.Encode(issues)
+	}
+	for _, issue := range issues {
+		fmt.Fprintf(w, "%s: %s: %s\n", source, issue.Check, issue.Message)
+	}
+	return nil
+}
+
+func init() {
+	Register_verb(&Verb{
+		Name:    "lint",
+		Summary: "Check USAGE for problems before they hit at runtime.",
+		Usage: `Check USAGE for problems which otherwise only show up at runtime, when
+some argv reaches them. One line per problem is reported: source, check
+name and message. The exit status is 1 if a problem is found, for CI.
+
+Checks:
+  syntax              USAGE isn't valid docopt, or an invalid annotation.
+  duplicate-option    An option is described twice.
+  duplicate-default   An option description has more than one default.
+  undescribed-option  An option is used in a pattern without description.
+  unused-option       An option is described but not used by any pattern.
+  unmangleable        A name can't be a variable name, like - or -4.
+  collision           Names mangled to the same variable: <dry-run> and
+                      --dry-run.
+  ambiguous-pattern   A pattern is never matched: an earlier alternative
+                      accepts all its arguments, like: prog <src> | prog <dst>
+                      or: prog <a> [<b>] | prog <a> <b>
+
+Usage:
+  docopts lint [options] USAGE
+  docopts lint [options] -f FILENAME
+
+Arguments:
+  USAGE                      The help message in docopt format.
+                             If - is given, read it from standard input.
+
+Options:
+  -h, --help                 Show this help.
+  -f, --file=FILENAME        Check the help message in the comments of the
+                             script FILENAME, see: docopts extract --help.
+  -G <prefix>                Check the names as mangled with this prefix.
+  --ignore=<checks>          Comma separated names of the checks to skip.
+  --json                     Output the problems as a JSON array of objects
+                             with check, key and message.
+
+Examples:
+  docopts lint -f myscript
+  docopts lint --json --ignore=unused-option "$usage"
+`,
+		Run: func(v *Verb, argv []string) {
+			arguments := v.Parse(argv)
+			source := "USAGE"
+			var doc string
+			if filename, _ := arguments.String("--file"); filename != "" {
+				bytes, err := ioutil.ReadFile(filename)
+				if err != nil {
+					docopts_error("lint: %v", err)
+				}
+				doc, err = Extract_usage(string(bytes))
+				if err != nil {
+					docopts_error("lint: %v", fmt.Errorf("%s: %v", filename, err))
+				}
+				source = filename
+			} else {
+				doc = arguments["USAGE"].(string)
+				if doc == "-" {
+					bytes, _ := ioutil.ReadAll(os.Stdin)
+					doc = string(bytes)
+				}
+			}
+			prefix, _ := arguments.String("-G")
+			issues := Lint(strings.TrimSpace(doc), prefix)
+			if ignore, _ := arguments.String("--ignore"); ignore != "" {
+				var err error
+				issues, err = Lint_filter(issues, strings.Split(ignore, ","))
+				if err != nil {
+					docopts_error("lint: %v", err)
+				}
+			}
+			if err := Print_lint(out, source, issues, arguments["--json"].(bool)); err != nil {
+				docopts_error("lint: %v", err)
+			}
+			if len(issues) > 0 {
+				os.Exit(1)
+			}
+		},
+	})
+}
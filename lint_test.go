@@ -0,0 +1,115 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for lint.go
+//
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+var naval_fate_usage = `Naval Fate.
+
+Usage:
+  naval_fate ship new <name>...
+  naval_fate ship <name> move <x> <y> [--speed=<kn>]
+  naval_fate ship shoot <x> <y>
+  naval_fate mine (set|remove) <x> <y> [--moored|--drifting]
+  naval_fate -h | --help
+  naval_fate --version
+
+Options:
+  -h --help     Show this screen.
+  --version     Show version.
+  --speed=<kn>  Speed in knots [default: 10].
+  --moored      Moored (anchored) mine.
+  --drifting    Drifting mine.
+`
+
+func lint_checks(issues []*Lint_issue) []string {
+	checks := []string{}
+	for _, issue := range issues {
+		checks = append(checks, issue.Check+" "+issue.Key)
+	}
+	return checks
+}
+
+func TestLint(t *testing.T) {
+	tables := []struct {
+		prefix string
+		doc    string
+		expect []string
+	}{
+		{"", "Usage: prog [options] <file>\n\nOptions:\n  -v  Verbose.", []string{}},
+		{"", "Usage: prog (", []string{"syntax "}},
+		{"", "Usage: prog [--speed=<kn>]\n\nOptions:\n  --speed=<kn>  Speed [type: nope].",
+			[]string{"syntax --speed"}},
+		{"", "Usage: prog [-v]\n\nOptions:\n  -v, --verbose  Verbose [env: VERBOSE].",
+			[]string{"syntax --verbose"}},
+		{"", "Usage: prog [--speed=<kn>]\n\nOptions:\n  --speed=<kn>  Speed [default: 10] [type: int].",
+			[]string{"syntax --speed"}},
+		{"", "Usage: prog [options]\n\nOptions:\n  -v  Verbose.\n  -v  Again.",
+			[]string{"duplicate-option -v"}},
+		{"", "Usage: prog [--speed=<kn>]\n\nOptions:\n  --speed=<kn>  Speed [default: 1] [default: 2].",
+			[]string{"duplicate-default --speed"}},
+		{"", "Usage: prog [-x] [-v]\n\nOptions:\n  -v  Verbose.\n  -q  Quiet.",
+			[]string{"undescribed-option -x", "unused-option -q"}},
+		{"", "Usage: prog [-4] <a> -", []string{"undescribed-option -4", "unmangleable -4", "unmangleable -"}},
+		{"p", "Usage: prog <a> -", []string{}},
+		{"", "Usage: prog [--dry-run] <dry-run>", []string{"undescribed-option --dry-run", "collision <dry-run>"}},
+		{"", "Usage: prog -- <a>...", []string{}},
+		{"", "Usage:\n  prog <src>\n  prog <dst>\n  prog x (<a> | <b>...)\n  prog y (<a> | <b>)",
+			[]string{"ambiguous-pattern prog <dst>", "ambiguous-pattern <b>"}},
+		// an earlier pattern accepting all the arguments of a later one
+		{"", "Usage:\n  prog <a> [<b>]\n  prog <a> <b>", []string{"ambiguous-pattern prog <a> <b>"}},
+		{"", "Usage:\n  prog [-v] <a>\n  prog -v <a>\n  prog <a> -v\n\nOptions:\n  -v  Verbose.",
+			[]string{"ambiguous-pattern prog -v <a>", "ambiguous-pattern prog <a> -v"}},
+		{"", "Usage:\n  prog <file>\n  prog run\n  prog -- <a>", []string{"ambiguous-pattern prog run"}},
+		{"", "Usage:\n  prog run\n  prog <a> <b>\n  prog <a> [<b>]\n  prog <a>...", []string{}},
+		// synonyms of one option aren't ambiguous
+		{"", "Usage:\n  prog -h | --help\n  prog (-v | --verbose) <a>\n\nOptions:\n  -h --help     Help.\n  -v --verbose  Verbose.",
+			[]string{}},
+		{"", "Usage: prog (-v | -q)\n\nOptions:\n  -v  Verbose.\n  -q  Quiet.", []string{}},
+		{"", naval_fate_usage, []string{}},
+	}
+	for _, table := range tables {
+		if res := lint_checks(Lint(table.doc, table.prefix)); !reflect.DeepEqual(res, table.expect) {
+			t.Errorf("Lint(%q, %q) got: %q want: %q", table.doc, table.prefix, res, table.expect)
+		}
+	}
+}
+
+func TestLint_filter(t *testing.T) {
+	issues := Lint("Usage: prog [-x] [-v]\n\nOptions:\n  -v  Verbose.\n  -q  Quiet.", "")
+	kept, err := Lint_filter(issues, []string{"unused-option"})
+	if err != nil {
+		t.Fatalf("Lint_filter: %v", err)
+	}
+	if res := lint_checks(kept); !reflect.DeepEqual(res, []string{"undescribed-option -x"}) {
+		t.Errorf("Lint_filter got: %q", res)
+	}
+	if _, err := Lint_filter(issues, []string{"nope"}); err == nil {
+		t.Errorf("Lint_filter with unknown check: error expected")
+	}
+}
+
+func TestPrint_lint(t *testing.T) {
+	issues := []*Lint_issue{{"unmangleable", "-", "Mangling not supported for: '-'"}}
+	var out bytes.Buffer
+	Print_lint(&out, "script.sh", issues, false)
+	if expect := "script.sh: unmangleable: Mangling not supported for: '-'\n"; out.String() != expect {
+		t.Errorf("Print_lint got: %q want: %q", out.String(), expect)
+	}
+	out.Reset()
+	Print_lint(&out, "script.sh", issues, true)
+	if expect := `[{"check":"unmangleable","key":"-","message":"Mangling not supported for: '-'"}]` + "\n"; out.String() != expect {
+		t.Errorf("Print_lint json got: %q want: %q", out.String(), expect)
+	}
+	out.Reset()
+	Print_lint(&out, "script.sh", []*Lint_issue{}, true)
+	if out.String() != "[]\n" {
+		t.Errorf("Print_lint json without issue got: %q", out.String())
+	}
+}
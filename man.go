@@ -0,0 +1,317 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// man.go: generate a man page, roff man(7) format, from a docopt usage.
+//
+package main
+
+import (
+	"fmt"
+	"github.com/docopt/docopts/pkg/grammar"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// A man page built from a parsed usage.
+type Man_page struct {
+	Doc *grammar.Doc
+	// program name, the usage's program name if empty
+	Name string
+	// manual section, 1 for commands
+	Section string
+	// the VERSION section, omitted if empty
+	Version string
+}
+
+// A free text section of the help message: a title line ending with a colon
+// and the indented lines following it.
+type Doc_section struct {
+	Title string
+	Lines []string
+}
+
+var re_section_title = regexp.MustCompile(`^(\S[^:]*):\s*$`)
+
+// roff highlighting of the usage patterns: options bold, <arguments> italic
+var re_man_option = regexp.MustCompile(`(^|[\s\[(|])(--?[A-Za-z0-9?][\w-]*)`)
+var re_man_argument = regexp.MustCompile(`<[^>]+>`)
+
+// the default is displayed on its own line
+var re_man_default = regexp.MustCompile(`(?i)\s*\[default: [^\]]*\]`)
+
+// Doc_sections splits the help message text into the free text, outside of
+// sections, and the sections other than usage and options.
+func Doc_sections(text string) (free []string, sections []*Doc_section) {
+	var current *Doc_section
+	in_skipped := false
+	for _, line := range strings.Split(text, "\n") {
+		indented := line == "" || line[0] == ' ' || line[0] == '\t'
+		if indented {
+			switch {
+			case current != nil:
+				current.Lines = append(current.Lines, line)
+			case !in_skipped:
+				free = append(free, line)
+			}
+			continue
+		}
+		current, in_skipped = nil, false
+		lower := strings.ToLower(line)
+		if strings.HasPrefix(lower, "usage:") || strings.Contains(lower, "options:") {
+			in_skipped = true
+			continue
+		}
+		if m := re_section_title.FindStringSubmatch(line); m != nil {
+			current = &Doc_section{Title: m[1]}
+			sections = append(sections, current)
+			continue
+		}
+		free = append(free, line)
+	}
+	return trim_blank_lines(free), sections
+}
+
+// removes the leading and trailing empty lines
+func trim_blank_lines(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// removes the indentation common to all non empty lines
+func dedent(lines []string) []string {
+	indent := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		n := len(l) - len(strings.TrimLeft(l, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		if len(l) >= indent && indent > 0 {
+			l = l[indent:]
+		}
+		out[i] = strings.TrimRight(l, " \t")
+	}
+	return out
+}
+
+// Man_escape escapes a line of text for roff: backslashes, and control
+// characters at the start of the line.
+func Man_escape(line string) string {
+	line = strings.Replace(line, `\`, `\e`, -1)
+	if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+		line = `\&` + line
+	}
+	return line
+}
+
+func man_bold(s string) string {
+	return `\fB` + strings.Replace(s, "-", `\-`, -1) + `\fR`
+}
+
+func man_italic(s string) string {
+	return `\fI` + s + `\fR`
+}
+
+// a usage pattern line, program name and options in bold, arguments in italic
+func (m *Man_page) synopsis_line(line string) string {
+	rest := ""
+	if fields := strings.SplitN(line, " ", 2); len(fields) == 2 {
+		rest = Man_escape(fields[1])
+	}
+	rest = re_man_argument.ReplaceAllStringFunc(rest, man_italic)
+	rest = re_man_option.ReplaceAllStringFunc(rest, func(s string) string {
+		m := re_man_option.FindStringSubmatch(s)
+		return m[1] + man_bold(m[2])
+	})
+	return strings.TrimSpace(man_bold(m.Name) + " " + rest)
+}
+
+// the option names and argument of an OPTIONS entry
+func man_option_names(o *grammar.Option) string {
+	names := []string{}
+	if o.Short != "" {
+		names = append(names, man_bold(o.Short))
+	}
+	if o.Long != "" {
+		names = append(names, man_bold(o.Long))
+	}
+	s := strings.Join(names, ", ")
+	if o.Argcount > 0 {
+		if o.Long != "" {
+			s += "=" + man_italic(o.Arg_name)
+		} else {
+			s += " " + man_italic(o.Arg_name)
+		}
+	}
+	return s
+}
+
+// preformatted lines, as written in the help message
+func man_preformatted(w io.Writer, lines []string) {
+	fmt.Fprintln(w, ".nf")
+	for _, l := range lines {
+		fmt.Fprintln(w, Man_escape(l))
+	}
+	fmt.Fprintln(w, ".fi")
+}
+
+// Roff outputs the man page: NAME, SYNOPSIS from the usage patterns,
+// DESCRIPTION from the free text, OPTIONS with their default, the other
+// sections of the help message, then VERSION.
+func (m *Man_page) Roff(w io.Writer) {
+	if m.Name == "" {
+		m.Name = m.Doc.Prog
+	}
+	if m.Section == "" {
+		m.Section = "1"
+	}
+	free, sections := Doc_sections(m.Doc.Text)
+	source := strings.SplitN(m.Version, "\n", 2)[0]
+	fmt.Fprintf(w, ".TH \"%s\" \"%s\" \"\" \"%s\"\n", strings.ToUpper(m.Name), m.Section, Man_escape(source))
+
+	// NAME: the first line of free text is the one line description
+	fmt.Fprintln(w, ".SH NAME")
+	if len(free) > 0 {
+		fmt.Fprintf(w, "%s \\- %s\n", m.Name, Man_escape(strings.TrimSpace(free[0])))
+		free = trim_blank_lines(free[1:])
+	} else {
+		fmt.Fprintln(w, m.Name)
+	}
+
+	fmt.Fprintln(w, ".SH SYNOPSIS")
+	fmt.Fprintln(w, ".nf")
+	for _, line := range m.Doc.Usage_lines {
+		fmt.Fprintln(w, m.synopsis_line(line))
+	}
+	fmt.Fprintln(w, ".fi")
+
+	if len(free) > 0 {
+		fmt.Fprintln(w, ".SH DESCRIPTION")
+		for _, line := range free {
+			if strings.TrimSpace(line) == "" {
+				fmt.Fprintln(w, ".PP")
+			} else {
+				fmt.Fprintln(w, Man_escape(strings.TrimSpace(line)))
+			}
+		}
+	}
+
+	options_title := false
+	for _, o := range m.Doc.Options {
+		if !o.Described {
+			continue
+		}
+		if !options_title {
+			fmt.Fprintln(w, ".SH OPTIONS")
+			options_title = true
+		}
+		fmt.Fprintln(w, ".TP")
+		fmt.Fprintln(w, man_option_names(o))
+		description := strings.TrimSpace(re_man_default.ReplaceAllString(o.Description, ""))
+		if description != "" {
+			fmt.Fprintln(w, Man_escape(description))
+		}
+		if o.Has_default {
+			if description != "" {
+				fmt.Fprintln(w, ".br")
+			}
+			fmt.Fprintf(w, "Default: %s\n", man_italic(Man_escape(o.Default)))
+		}
+	}
+
+	for _, s := range sections {
+		lines := trim_blank_lines(dedent(s.Lines))
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, ".SH %s\n", strings.ToUpper(Man_escape(s.Title)))
+		man_preformatted(w, lines)
+	}
+
+	if m.Version != "" {
+		fmt.Fprintln(w, ".SH VERSION")
+		man_preformatted(w, strings.Split(strings.TrimSpace(m.Version), "\n"))
+	}
+}
+
+func init() {
+	Register_verb(&Verb{
+		Name:    "man",
+		Summary: "Generate a man page from USAGE.",
+		Usage: `Generate a man page from USAGE, in roff man(7) format.
+
+The first line of text before the usage section is the NAME description, the
+usage patterns are the SYNOPSIS, the other free text is the DESCRIPTION. The
+described options are listed in OPTIONS with their default. Other sections of
+USAGE, like Arguments or Examples, are kept as written. The version message is
+the VERSION section.
+
+Usage:
+  docopts man [options] USAGE
+  docopts man [options] -f FILENAME
+
+Arguments:
+  USAGE                      The help message in docopt format.
+                             If - is given, read it from standard input.
+
+Options:
+  -h, --help                 Show this help.
+  -f, --file=FILENAME        Read the help message and the version from the
+                             comments of the script FILENAME, see: docopts
+                             extract --help.
+  -n <name>, --name=<name>   The program name, default is the program name
+                             of USAGE.
+  -V <msg>, --version=<msg>  The version message.
+  -s <n>, --section=<n>      The manual section. [default: 1]
+
+Examples:
+  docopts man -V "$version" "$usage" > myscript.1
+  docopts man -f myscript | man -l -
+`,
+		Run: func(v *Verb, argv []string) {
+			arguments := v.Parse(argv)
+			m := &Man_page{}
+			var doc string
+			if filename, _ := arguments.String("--file"); filename != "" {
+				bytes, err := ioutil.ReadFile(filename)
+				if err != nil {
+					docopts_error("man: %v", err)
+				}
+				doc, err = Extract_usage(string(bytes))
+				if err != nil {
+					docopts_error("man: %v", fmt.Errorf("%s: %v", filename, err))
+				}
+				m.Version = Extract_version(string(bytes))
+			} else {
+				doc = arguments["USAGE"].(string)
+				if doc == "-" {
+					bytes, _ := ioutil.ReadAll(os.Stdin)
+					doc = string(bytes)
+				}
+			}
+			parsed, err := grammar.Parse_doc(strings.TrimSpace(doc))
+			if err != nil {
+				docopts_error("man: USAGE: %v", err)
+			}
+			m.Doc = parsed
+			m.Name, _ = arguments.String("--name")
+			m.Section, _ = arguments.String("--section")
+			if version, _ := arguments.String("--version"); version != "" {
+				m.Version = version
+			}
+			m.Roff(out)
+		},
+	})
+}
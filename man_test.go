@@ -0,0 +1,117 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for man.go
+//
+package main
+
+import (
+	"bytes"
+	"github.com/docopt/docopts/pkg/grammar"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var man_usage = `Naval Fate, ship management.
+
+Moves ships.
+
+Usage:
+  naval_fate ship <name> move [--speed=<kn>]
+  naval_fate -h | --help
+
+Arguments:
+  <name>  Ship name.
+
+Options:
+  -h --help     Show this screen.
+  --speed=<kn>  Speed in knots [default: 10].
+
+Examples:
+  naval_fate ship Guardian move
+  .dotted \ line
+`
+
+func TestDoc_sections(t *testing.T) {
+	free, sections := Doc_sections(man_usage)
+	if expect := []string{"Naval Fate, ship management.", "", "Moves ships."}; !reflect.DeepEqual(free, expect) {
+		t.Errorf("Doc_sections free text got: %q want: %q", free, expect)
+	}
+	if len(sections) != 2 || sections[0].Title != "Arguments" || sections[1].Title != "Examples" {
+		t.Errorf("Doc_sections got: %+v", sections)
+	}
+}
+
+func TestMan_escape(t *testing.T) {
+	tables := []struct {
+		line   string
+		expect string
+	}{
+		{"plain - text", "plain - text"},
+		{`a\b`, `a\eb`},
+		{".TH x", `\&.TH x`},
+		{"'quote", `\&'quote`},
+	}
+	for _, table := range tables {
+		if res := Man_escape(table.line); res != table.expect {
+			t.Errorf("Man_escape(%q) got: %q want: %q", table.line, res, table.expect)
+		}
+	}
+}
+
+func TestMan_page_Roff(t *testing.T) {
+	doc, err := grammar.Parse_doc(man_usage)
+	if err != nil {
+		t.Fatalf("Parse_doc: %v", err)
+	}
+	var out bytes.Buffer
+	m := &Man_page{Doc: doc, Version: "naval_fate 2.0\n(c) me"}
+	m.Roff(&out)
+	expect := `.TH "NAVAL_FATE" "1" "" "naval_fate 2.0"
+.SH NAME
+naval_fate \- Naval Fate, ship management.
+.SH SYNOPSIS
+.nf
+\fBnaval_fate\fR ship \fI<name>\fR move [\fB\-\-speed\fR=\fI<kn>\fR]
+\fBnaval_fate\fR \fB\-h\fR | \fB\-\-help\fR
+.fi
+.SH DESCRIPTION
+Moves ships.
+.SH OPTIONS
+.TP
+\fB\-h\fR, \fB\-\-help\fR
+Show this screen.
+.TP
+\fB\-\-speed\fR=\fI<kn>\fR
+Speed in knots.
+.br
+Default: \fI10\fR
+.SH ARGUMENTS
+.nf
+<name>  Ship name.
+.fi
+.SH EXAMPLES
+.nf
+naval_fate ship Guardian move
+\&.dotted \e line
+.fi
+.SH VERSION
+.nf
+naval_fate 2.0
+(c) me
+.fi
+`
+	if out.String() != expect {
+		t.Errorf("Roff\ngot: '%s'\nwant: '%s'", out.String(), expect)
+	}
+
+	// no free text, no version, name and section given
+	doc, _ = grammar.Parse_doc("Usage: prog [-v]")
+	out.Reset()
+	m = &Man_page{Doc: doc, Name: "other", Section: "8"}
+	m.Roff(&out)
+	if res := out.String(); !strings.HasPrefix(res, ".TH \"OTHER\" \"8\" \"\" \"\"\n.SH NAME\nother\n.SH SYNOPSIS\n") ||
+		strings.Contains(res, "DESCRIPTION") || strings.Contains(res, "VERSION") {
+		t.Errorf("Roff without free text got: '%s'", res)
+	}
+}
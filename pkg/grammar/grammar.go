@@ -0,0 +1,559 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// grammar parses a docopt help message into its usage patterns and options.
+//
+// docopt-go doesn't export its pattern tree, this package follows the same
+// parsing rules so docopts can inspect the usage: completion, documentation,
+// error reporting, etc. Parsing argv is still done by docopt-go.
+//
+package grammar
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+type Pattern_type int
+
+const (
+	// leaf
+	Argument Pattern_type = iota
+	Command
+	Option_leaf
+	// branch
+	Required
+	Optional
+	Options_shortcut
+	One_or_more
+	Either
+)
+
+func (t Pattern_type) String() string {
+	switch t {
+	case Argument:
+		return "Argument"
+	case Command:
+		return "Command"
+	case Option_leaf:
+		return "Option"
+	case Required:
+		return "Required"
+	case Optional:
+		return "Optional"
+	case Options_shortcut:
+		return "OptionsShortcut"
+	case One_or_more:
+		return "OneOrMore"
+	case Either:
+		return "Either"
+	}
+	return fmt.Sprintf("Pattern_type(%d)", int(t))
+}
+
+// An option as described in an Options: section, or only found in usage patterns.
+type Option struct {
+	Short    string
+	Long     string
+	Argcount int
+	// the argument placeholder: --speed=<kn> gives <kn>
+	Arg_name    string
+	Default     string
+	Has_default bool
+	// description text, whitespaces are collapsed
+	Description string
+	// false if the option is only found in usage patterns
+	Described bool
+	// value annotations, see types.go: [type: int], [type: path, exists],
+	// [choices: fast slow] and [pattern: ^[a-z]+$]
+	Type    string
+	Exists  bool
+	Choices []string
+	Pattern string
+	// environment variable used when the option isn't given: [env: NAME]
+	Env string
+}
+
+// Name is the key used by docopt for the parsed value: long name if any.
+func (o *Option) Name() string {
+	if o.Long != "" {
+		return o.Long
+	}
+	return o.Short
+}
+
+// Names returns short and long names, if defined.
+func (o *Option) Names() []string {
+	names := []string{}
+	if o.Short != "" {
+		names = append(names, o.Short)
+	}
+	if o.Long != "" {
+		names = append(names, o.Long)
+	}
+	return names
+}
+
+type Pattern struct {
+	Type     Pattern_type
+	Children []*Pattern
+	// leaf name: <arg>, ARG, command, or the option's Name()
+	Name string
+	// only for Option_leaf
+	Option *Option
+}
+
+func (p *Pattern) Is_leaf() bool {
+	return p.Type == Argument || p.Type == Command || p.Type == Option_leaf
+}
+
+// Leaves returns all leaves in pattern order, the Options_shortcut children are
+// included.
+func (p *Pattern) Leaves() []*Pattern {
+	if p.Is_leaf() {
+		return []*Pattern{p}
+	}
+	leaves := []*Pattern{}
+	for _, c := range p.Children {
+		leaves = append(leaves, c.Leaves()...)
+	}
+	return leaves
+}
+
+// String gives the pattern in docopt syntax.
+func (p *Pattern) String() string {
+	children := make([]string, len(p.Children))
+	for i, c := range p.Children {
+		children[i] = c.String()
+	}
+	switch p.Type {
+	case Argument, Command:
+		return p.Name
+	case Option_leaf:
+		if p.Option.Argcount > 0 {
+			if strings.HasPrefix(p.Name, "--") {
+				return p.Name + "=" + p.Option.Arg_name
+			}
+			return p.Name + " " + p.Option.Arg_name
+		}
+		return p.Name
+	case Required:
+		return "(" + strings.Join(children, " ") + ")"
+	case Optional:
+		return "[" + strings.Join(children, " ") + "]"
+	case Options_shortcut:
+		return "options"
+	case One_or_more:
+		return strings.Join(children, " ") + "..."
+	case Either:
+		return strings.Join(children, " | ")
+	}
+	return ""
+}
+
+// A parsed docopt help message.
+type Doc struct {
+	Text string
+	// the Usage: section
+	Usage_section string
+	// program name: the first word of the usage
+	Prog string
+	// one entry per usage pattern, as written
+	Usage_lines []string
+	// described options first, in Options: section order, then options only
+	// found in patterns
+	Options []*Option
+	// Required(Either(Required(...) ...)) one Required per usage line
+	Pattern *Pattern
+}
+
+// Parse_doc parses a docopt help message, errors are docopt language errors.
+func Parse_doc(doc string) (*Doc, error) {
+	usage_sections := Parse_section("usage:", doc)
+	if len(usage_sections) == 0 {
+		return nil, fmt.Errorf("\"usage:\" (case-insensitive) not found.")
+	}
+	if len(usage_sections) > 1 {
+		return nil, fmt.Errorf("More than one \"usage:\" (case-insensitive).")
+	}
+
+	d := &Doc{
+		Text:          doc,
+		Usage_section: usage_sections[0],
+		Options:       Parse_options(doc),
+	}
+
+	_, _, section := string_partition(d.Usage_section, ":")
+	fields := strings.Fields(section)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields found in usage (perhaps a spacing error).")
+	}
+	d.Prog = fields[0]
+
+	// formal usage: ( line1 ) | ( line2 ) ...
+	formal := "( "
+	line := []string{d.Prog}
+	for _, f := range fields[1:] {
+		if f == d.Prog {
+			formal += ") | ( "
+			d.Usage_lines = append(d.Usage_lines, strings.Join(line, " "))
+			line = []string{d.Prog}
+		} else {
+			formal += f + " "
+			line = append(line, f)
+		}
+	}
+	formal += ")"
+	d.Usage_lines = append(d.Usage_lines, strings.Join(line, " "))
+
+	tokens := tokens_from_pattern(formal)
+	result, err := d.parse_expr(tokens)
+	if err != nil {
+		return nil, err
+	}
+	if tokens.current() != "" {
+		return nil, fmt.Errorf("unexpected ending: %s", strings.Join(tokens.tokens, " "))
+	}
+	d.Pattern = &Pattern{Type: Required, Children: result}
+
+	// [options] stands for all described options not already in patterns
+	in_pattern := make(map[*Option]bool)
+	for _, l := range d.Pattern.Leaves() {
+		if l.Type == Option_leaf {
+			in_pattern[l.Option] = true
+		}
+	}
+	d.walk(d.Pattern, func(p *Pattern) {
+		if p.Type == Options_shortcut {
+			p.Children = []*Pattern{}
+			for _, o := range d.Options {
+				if o.Described && !in_pattern[o] {
+					p.Children = append(p.Children, &Pattern{Type: Option_leaf, Name: o.Name(), Option: o})
+				}
+			}
+		}
+	})
+
+	return d, nil
+}
+
+func (d *Doc) walk(p *Pattern, f func(*Pattern)) {
+	f(p)
+	for _, c := range p.Children {
+		d.walk(c, f)
+	}
+}
+
+// Find_option returns the option matching name, short or long, nil if not found.
+func (d *Doc) Find_option(name string) *Option {
+	for _, o := range d.Options {
+		if o.Short == name || o.Long == name {
+			return o
+		}
+	}
+	return nil
+}
+
+// Commands returns all command names in usage order, without duplicate.
+func (d *Doc) Commands() []string {
+	return d.leaf_names(Command)
+}
+
+// Arguments returns all positional argument names in usage order, without duplicate.
+func (d *Doc) Arguments() []string {
+	return d.leaf_names(Argument)
+}
+
+func (d *Doc) leaf_names(t Pattern_type) []string {
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, l := range d.Pattern.Leaves() {
+		if l.Type == t && !seen[l.Name] {
+			seen[l.Name] = true
+			names = append(names, l.Name)
+		}
+	}
+	return names
+}
+
+// Parse_section returns all blocs starting at a line containing name and
+// ending before the first non indented line. Same rule as docopt.
+func Parse_section(name, source string) []string {
+	p := regexp.MustCompile(`(?im)^([^\n]*` + name + `[^\n]*\n?(?:[ \t].*?(?:\n|$))*)`)
+	s := p.FindAllString(source, -1)
+	if s == nil {
+		s = []string{}
+	}
+	for i, v := range s {
+		s[i] = strings.TrimSpace(v)
+	}
+	return s
+}
+
+// Parse_options returns the options described in all Options: sections.
+func Parse_options(doc string) []*Option {
+	options := []*Option{}
+	p := regexp.MustCompile(`\n[ \t]*(-\S+?)`)
+	for _, s := range Parse_section("options:", doc) {
+		_, _, s = string_partition(s, ":")
+		split := p.Split("\n"+s, -1)[1:]
+		match := p.FindAllStringSubmatch("\n"+s, -1)
+		for i := range split {
+			description := match[i][1] + split[i]
+			if strings.HasPrefix(description, "-") {
+				options = append(options, Parse_option(description))
+			}
+		}
+	}
+	return options
+}
+
+var re_default = regexp.MustCompile(`(?i)\[default: (.*)\]`)
+
+// Parse_option parses a single option description line, as found in an
+// Options: section.
+func Parse_option(description string) *Option {
+	description = strings.TrimSpace(description)
+	names, _, text := string_partition(description, "  ")
+	names = strings.Replace(names, ",", " ", -1)
+	names = strings.Replace(names, "=", " ", -1)
+
+	o := &Option{
+		Description: strings.Join(strings.Fields(text), " "),
+		Described:   true,
+	}
+	for _, s := range strings.Fields(names) {
+		if strings.HasPrefix(s, "--") {
+			o.Long = s
+		} else if strings.HasPrefix(s, "-") {
+			o.Short = s
+		} else {
+			o.Argcount = 1
+			o.Arg_name = s
+		}
+	}
+	if o.Argcount > 0 {
+		matched := re_default.FindStringSubmatch(text)
+		if matched != nil {
+			o.Default = matched[1]
+			o.Has_default = true
+		}
+	}
+	// annotations of an option without argument are reported by
+	// Check_annotations()
+	o.parse_annotations(text)
+	return o
+}
+
+// tokens of a usage pattern
+type token_list struct {
+	tokens []string
+}
+
+func tokens_from_pattern(source string) *token_list {
+	p := regexp.MustCompile(`([\[\]\(\)\|]|\.\.\.)`)
+	source = p.ReplaceAllString(source, ` $1 `)
+	p = regexp.MustCompile(`\s+|(\S*<.*?>)`)
+	split := p.Split(source, -1)
+	match := p.FindAllStringSubmatch(source, -1)
+	result := []string{}
+	for i := 0; i < len(split); i++ {
+		if len(split[i]) > 0 {
+			result = append(result, split[i])
+		}
+		if i < len(split)-1 && len(match[i][1]) > 0 {
+			result = append(result, match[i][1])
+		}
+	}
+	return &token_list{result}
+}
+
+// current token or "" at the end
+func (t *token_list) current() string {
+	if len(t.tokens) > 0 {
+		return t.tokens[0]
+	}
+	return ""
+}
+
+func (t *token_list) move() string {
+	tok := t.current()
+	if len(t.tokens) > 0 {
+		t.tokens = t.tokens[1:]
+	}
+	return tok
+}
+
+// expr ::= seq ( '|' seq )* ;
+func (d *Doc) parse_expr(tokens *token_list) ([]*Pattern, error) {
+	seq, err := d.parse_seq(tokens)
+	if err != nil {
+		return nil, err
+	}
+	if tokens.current() != "|" {
+		return seq, nil
+	}
+	var result []*Pattern
+	if len(seq) > 1 {
+		result = []*Pattern{{Type: Required, Children: seq}}
+	} else {
+		result = seq
+	}
+	for tokens.current() == "|" {
+		tokens.move()
+		seq, err = d.parse_seq(tokens)
+		if err != nil {
+			return nil, err
+		}
+		if len(seq) > 1 {
+			result = append(result, &Pattern{Type: Required, Children: seq})
+		} else {
+			result = append(result, seq...)
+		}
+	}
+	if len(result) > 1 {
+		return []*Pattern{{Type: Either, Children: result}}, nil
+	}
+	return result, nil
+}
+
+// seq ::= ( atom [ '...' ] )* ;
+func (d *Doc) parse_seq(tokens *token_list) ([]*Pattern, error) {
+	result := []*Pattern{}
+	for {
+		tok := tokens.current()
+		if tok == "" || tok == "]" || tok == ")" || tok == "|" {
+			break
+		}
+		atom, err := d.parse_atom(tokens)
+		if err != nil {
+			return nil, err
+		}
+		if tokens.current() == "..." {
+			atom = []*Pattern{{Type: One_or_more, Children: atom}}
+			tokens.move()
+		}
+		result = append(result, atom...)
+	}
+	return result, nil
+}
+
+// atom ::= '(' expr ')' | '[' expr ']' | 'options' | long | shorts | argument | command ;
+func (d *Doc) parse_atom(tokens *token_list) ([]*Pattern, error) {
+	tok := tokens.current()
+	switch {
+	case tok == "(" || tok == "[":
+		tokens.move()
+		children, err := d.parse_expr(tokens)
+		if err != nil {
+			return nil, err
+		}
+		matching := ")"
+		t := Required
+		if tok == "[" {
+			matching = "]"
+			t = Optional
+		}
+		if moved := tokens.move(); moved != matching {
+			return nil, fmt.Errorf("unmatched '%s', expected: '%s' got: '%s'", tok, matching, moved)
+		}
+		return []*Pattern{{Type: t, Children: children}}, nil
+	case tok == "options":
+		tokens.move()
+		return []*Pattern{{Type: Options_shortcut}}, nil
+	case strings.HasPrefix(tok, "--") && tok != "--":
+		return d.parse_long(tokens)
+	case strings.HasPrefix(tok, "-") && tok != "-" && tok != "--":
+		return d.parse_shorts(tokens)
+	case strings.HasPrefix(tok, "<") && strings.HasSuffix(tok, ">") || is_upper(tok):
+		return []*Pattern{{Type: Argument, Name: tokens.move()}}, nil
+	}
+	return []*Pattern{{Type: Command, Name: tokens.move()}}, nil
+}
+
+// long ::= '--' chars [ ( ' ' | '=' ) chars ] ;
+func (d *Doc) parse_long(tokens *token_list) ([]*Pattern, error) {
+	long, eq, value := string_partition(tokens.move(), "=")
+	var o *Option
+	for _, candidate := range d.Options {
+		if candidate.Long == long {
+			if o != nil {
+				return nil, fmt.Errorf("%s is not a unique prefix: %s, %s?", long, o.Long, candidate.Long)
+			}
+			o = candidate
+		}
+	}
+	if o == nil {
+		o = &Option{Long: long}
+		if eq == "=" {
+			o.Argcount = 1
+			o.Arg_name = value
+		}
+		d.Options = append(d.Options, o)
+	} else if o.Argcount == 0 {
+		if eq == "=" {
+			return nil, fmt.Errorf("%s must not have an argument", o.Long)
+		}
+	} else if eq == "" {
+		if tokens.current() == "" || tokens.current() == "--" {
+			return nil, fmt.Errorf("%s requires argument", o.Long)
+		}
+		tokens.move()
+	}
+	return []*Pattern{{Type: Option_leaf, Name: o.Name(), Option: o}}, nil
+}
+
+// shorts ::= '-' ( chars )* [ [ ' ' ] chars ] ;
+func (d *Doc) parse_shorts(tokens *token_list) ([]*Pattern, error) {
+	left := strings.TrimLeft(tokens.move(), "-")
+	parsed := []*Pattern{}
+	for left != "" {
+		short := "-" + left[0:1]
+		left = left[1:]
+		var o *Option
+		for _, candidate := range d.Options {
+			if candidate.Short == short {
+				if o != nil {
+					return nil, fmt.Errorf("%s is specified ambiguously 2 times", short)
+				}
+				o = candidate
+			}
+		}
+		if o == nil {
+			o = &Option{Short: short}
+			d.Options = append(d.Options, o)
+		} else if o.Argcount > 0 {
+			if left == "" {
+				if tokens.current() == "" || tokens.current() == "--" {
+					return nil, fmt.Errorf("%s requires argument", short)
+				}
+				tokens.move()
+			}
+			left = ""
+		}
+		parsed = append(parsed, &Pattern{Type: Option_leaf, Name: o.Name(), Option: o})
+	}
+	return parsed, nil
+}
+
+func string_partition(s, sep string) (string, string, string) {
+	split := strings.SplitN(s, sep, 2)
+	if len(split) == 1 {
+		return s, "", ""
+	}
+	return split[0], sep, split[1]
+}
+
+// true if all cased characters in s are uppercase and there is at least one
+func is_upper(s string) bool {
+	if strings.ToUpper(s) != s {
+		return false
+	}
+	for _, c := range s {
+		if unicode.IsUpper(c) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for grammar.go
+//
+package grammar
+
+import (
+	"reflect"
+	"testing"
+)
+
+var naval_fate = `Naval Fate.
+
+Usage:
+  naval_fate ship new <name>...
+  naval_fate ship <name> move <x> <y> [--speed=<kn>]
+  naval_fate ship shoot <x> <y>
+  naval_fate mine (set|remove) <x> <y> [--moored|--drifting]
+  naval_fate -h | --help
+  naval_fate --version
+
+Options:
+  -h --help     Show this screen.
+  --version     Show version.
+  --speed=<kn>  Speed in knots [default: 10].
+  --moored      Moored (anchored) mine.
+  --drifting    Drifting mine.
+`
+
+func TestParse_option(t *testing.T) {
+	tables := []struct {
+		input  string
+		expect Option
+	}{
+		{"-h", Option{Short: "-h", Described: true}},
+		{"--help", Option{Long: "--help", Described: true}},
+		{"-h --help  Show help.", Option{Short: "-h", Long: "--help", Description: "Show help.", Described: true}},
+		{"-o FILE  Output.", Option{Short: "-o", Argcount: 1, Arg_name: "FILE", Description: "Output.", Described: true}},
+		{"--speed=<kn>  Speed in knots\n     [default: 10].", Option{
+			Long: "--speed", Argcount: 1, Arg_name: "<kn>", Default: "10", Has_default: true,
+			Description: "Speed in knots [default: 10].", Described: true,
+		}},
+		{"-s <str>, --separator=<str>  Sep [default: ----]", Option{
+			Short: "-s", Long: "--separator", Argcount: 1, Arg_name: "<str>", Default: "----", Has_default: true,
+			Description: "Sep [default: ----]", Described: true,
+		}},
+		// no default for option without argument
+		{"-v  Verbose [default: 2]", Option{Short: "-v", Description: "Verbose [default: 2]", Described: true}},
+	}
+
+	for _, table := range tables {
+		o := Parse_option(table.input)
+		if !reflect.DeepEqual(*o, table.expect) {
+			t.Errorf("Parse_option for '%s'\ngot: '%+v'\nwant: '%+v'\n", table.input, *o, table.expect)
+		}
+	}
+}
+
+func TestParse_section(t *testing.T) {
+	doc := "Usage: prog\n\nOptions:\n  -a  A.\n  -b  B.\n\nNot options section.\n"
+	res := Parse_section("options:", doc)
+	expect := []string{"Options:\n  -a  A.\n  -b  B."}
+	if !reflect.DeepEqual(res, expect) {
+		t.Errorf("Parse_section\ngot: '%#v'\nwant: '%#v'\n", res, expect)
+	}
+}
+
+func TestParse_doc(t *testing.T) {
+	d, err := Parse_doc(naval_fate)
+	if err != nil {
+		t.Fatalf("Parse_doc error: %v", err)
+	}
+
+	if d.Prog != "naval_fate" {
+		t.Errorf("Prog got: '%s'", d.Prog)
+	}
+	expect_lines := []string{
+		"naval_fate ship new <name>...",
+		"naval_fate ship <name> move <x> <y> [--speed=<kn>]",
+		"naval_fate ship shoot <x> <y>",
+		"naval_fate mine (set|remove) <x> <y> [--moored|--drifting]",
+		"naval_fate -h | --help",
+		"naval_fate --version",
+	}
+	if !reflect.DeepEqual(d.Usage_lines, expect_lines) {
+		t.Errorf("Usage_lines\ngot: '%#v'\nwant: '%#v'\n", d.Usage_lines, expect_lines)
+	}
+
+	expect_commands := []string{"ship", "new", "move", "shoot", "mine", "set", "remove"}
+	if !reflect.DeepEqual(d.Commands(), expect_commands) {
+		t.Errorf("Commands\ngot: '%#v'\nwant: '%#v'\n", d.Commands(), expect_commands)
+	}
+	expect_arguments := []string{"<name>", "<x>", "<y>"}
+	if !reflect.DeepEqual(d.Arguments(), expect_arguments) {
+		t.Errorf("Arguments\ngot: '%#v'\nwant: '%#v'\n", d.Arguments(), expect_arguments)
+	}
+
+	expect_pattern := "((ship new <name>...) | (ship <name> move <x> <y> [--speed=<kn>]) | (ship shoot <x> <y>)" +
+		" | (mine (set | remove) <x> <y> [--moored | --drifting]) | (--help | --help) | (--version))"
+	if d.Pattern.String() != expect_pattern {
+		t.Errorf("Pattern\ngot: '%s'\nwant: '%s'\n", d.Pattern.String(), expect_pattern)
+	}
+
+	speed := d.Find_option("--speed")
+	if speed == nil || speed.Default != "10" || speed.Arg_name != "<kn>" {
+		t.Errorf("Find_option --speed got: '%+v'", speed)
+	}
+	if d.Find_option("-h") != d.Find_option("--help") {
+		t.Errorf("Find_option -h and --help must be the same option")
+	}
+}
+
+func TestParse_doc_undescribed_options(t *testing.T) {
+	d, err := Parse_doc("Usage: prog [options] [-q] --file=<f> <x>\n\nOptions:\n  -v  Verbose.\n  -q  Quiet.")
+	if err != nil {
+		t.Fatalf("Parse_doc error: %v", err)
+	}
+
+	file := d.Find_option("--file")
+	if file == nil || file.Described || file.Argcount != 1 || file.Arg_name != "<f>" {
+		t.Errorf("undescribed --file got: '%+v'", file)
+	}
+
+	expect := "(([options] [-q] --file=<f> <x>))"
+	if d.Pattern.String() != expect {
+		t.Errorf("Pattern\ngot: '%s'\nwant: '%s'\n", d.Pattern, expect)
+	}
+
+	// [options] only stands for options not found in patterns
+	leaves := d.Pattern.Leaves()
+	if len(leaves) != 4 || leaves[0].Name != "-v" {
+		t.Errorf("Leaves with [options] got: %v", leaves)
+	}
+}
+
+func TestParse_doc_errors(t *testing.T) {
+	tables := []string{
+		"no usage here",
+		"Usage: prog\nusage: prog2",
+		"Usage:",
+		"Usage: prog (a",
+		"Usage: prog [a)",
+		"Usage: prog --speed=<x>\n\nOptions:\n  --speed  No argument.",
+		"Usage: prog --speed\n\nOptions:\n  --speed=<kn>  Requires argument.",
+	}
+
+	for _, doc := range tables {
+		_, err := Parse_doc(doc)
+		if err == nil {
+			t.Errorf("Parse_doc expecting error for '%s'", doc)
+		}
+	}
+}
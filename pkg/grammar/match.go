@@ -0,0 +1,556 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// match.go: match argv against usage patterns to explain parse errors.
+//
+// The matching rules are the same as docopt's, but the result also records
+// how far each usage pattern went and what was missing, so a user error can
+// be named instead of only displaying the usage.
+//
+package grammar
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A parsed argv element: an option with its value or a positional argument.
+type Item struct {
+	// nil for positional argument
+	Option *Option
+	// option's argument or positional value, "" for option without argument
+	Value string
+	// argv token which produced this item
+	Token string
+}
+
+func (i *Item) String() string {
+	if i.Option == nil {
+		return i.Value
+	}
+	if i.Option.Argcount > 0 {
+		return i.Option.Name() + "=" + i.Value
+	}
+	return i.Option.Name()
+}
+
+type Error_kind int
+
+const (
+	Invalid_option Error_kind = iota
+	Ambiguous_option
+	Requires_value
+	Unexpected_value
+	Unknown_command
+	Unexpected_argument
+	Not_allowed
+	Repeated_option
+	Missing_command
+	Missing_argument
+	Missing_option
+	Invalid_value
+)
+
+// User_error names why argv doesn't match the usage.
+type User_error struct {
+	Kind Error_kind
+	// the offending argv token or option name
+	Token string
+	// what was expected instead: commands, options or arguments names
+	Expected []string
+	// expected names close to Token, for mistyped commands and options
+	Suggestions []string
+	msg         string
+}
+
+func (e *User_error) Error() string {
+	if len(e.Suggestions) > 0 {
+		return e.msg + ", did you mean " + quote_or(e.Suggestions) + "?"
+	}
+	return e.msg
+}
+
+// fill e.Suggestions from e.Expected
+func (e *User_error) suggest() *User_error {
+	e.Suggestions = Suggest(e.Token, e.Expected)
+	return e
+}
+
+func new_user_error(kind Error_kind, token string, expected []string, msg string, f ...interface{}) *User_error {
+	return &User_error{
+		Kind:     kind,
+		Token:    token,
+		Expected: expected,
+		msg:      fmt.Sprintf(msg, f...),
+	}
+}
+
+// Parse_argv splits argv into options and positional arguments, options are
+// resolved against d.Options. Same rules as docopt: long option unique prefix,
+// stacked short options and '--' stops option parsing.
+func (d *Doc) Parse_argv(argv []string, options_first bool) ([]*Item, error) {
+	items := []*Item{}
+	for i := 0; i < len(argv); i++ {
+		tok := argv[i]
+		switch {
+		case tok == "--":
+			// docopt keeps the '--' itself as an argument
+			for _, a := range argv[i:] {
+				items = append(items, &Item{Value: a, Token: a})
+			}
+			return items, nil
+		case strings.HasPrefix(tok, "--"):
+			item, consumed, err := d.parse_long_argv(argv[i:])
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			i += consumed
+		case strings.HasPrefix(tok, "-") && tok != "-":
+			shorts, consumed, err := d.parse_shorts_argv(argv[i:])
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, shorts...)
+			i += consumed
+		case options_first:
+			for _, a := range argv[i:] {
+				items = append(items, &Item{Value: a, Token: a})
+			}
+			return items, nil
+		default:
+			items = append(items, &Item{Value: tok, Token: tok})
+		}
+	}
+	return items, nil
+}
+
+// Long_options returns all long option names.
+func (d *Doc) Long_options() []string {
+	names := []string{}
+	for _, o := range d.Options {
+		if o.Long != "" {
+			names = append(names, o.Long)
+		}
+	}
+	return names
+}
+
+// Short_options returns all short option names.
+func (d *Doc) Short_options() []string {
+	names := []string{}
+	for _, o := range d.Options {
+		if o.Short != "" {
+			names = append(names, o.Short)
+		}
+	}
+	return names
+}
+
+// parse a long option at argv[0], returns the number of extra argv consumed
+func (d *Doc) parse_long_argv(argv []string) (*Item, int, error) {
+	long, eq, value := string_partition(argv[0], "=")
+	similar := []*Option{}
+	for _, o := range d.Options {
+		if o.Long == long {
+			similar = append(similar, o)
+		}
+	}
+	if len(similar) == 0 {
+		for _, o := range d.Options {
+			if strings.HasPrefix(o.Long, long) {
+				similar = append(similar, o)
+			}
+		}
+	}
+
+	if len(similar) > 1 {
+		names := make([]string, len(similar))
+		for i, o := range similar {
+			names[i] = o.Long
+		}
+		return nil, 0, new_user_error(Ambiguous_option, long, names,
+			"Ambiguous option '%s', it could be: %s", long, strings.Join(names, ", "))
+	}
+	if len(similar) == 0 {
+		return nil, 0, new_user_error(Invalid_option, long, d.Long_options(), "Invalid option '%s'", long).suggest()
+	}
+
+	o := similar[0]
+	item := &Item{Option: o, Token: argv[0]}
+	consumed := 0
+	if o.Argcount == 0 {
+		if eq == "=" {
+			return nil, 0, new_user_error(Unexpected_value, o.Long, nil, "%s doesn't take a value", o.Long)
+		}
+	} else {
+		if eq == "=" {
+			item.Value = value
+		} else {
+			if len(argv) < 2 || argv[1] == "--" {
+				return nil, 0, new_user_error(Requires_value, o.Long, nil, "%s requires a value", o.Long)
+			}
+			item.Value = argv[1]
+			consumed = 1
+		}
+	}
+	return item, consumed, nil
+}
+
+// parse stacked short options at argv[0], returns the number of extra argv consumed
+func (d *Doc) parse_shorts_argv(argv []string) ([]*Item, int, error) {
+	items := []*Item{}
+	consumed := 0
+	left := strings.TrimLeft(argv[0], "-")
+	for left != "" {
+		short := "-" + left[0:1]
+		left = left[1:]
+		var o *Option
+		for _, candidate := range d.Options {
+			if candidate.Short == short {
+				o = candidate
+				break
+			}
+		}
+		if o == nil {
+			return nil, 0, new_user_error(Invalid_option, short, d.Short_options(), "Invalid option '%s'", short)
+		}
+		item := &Item{Option: o, Token: argv[0]}
+		if o.Argcount > 0 {
+			if left == "" {
+				if len(argv) < 2 || argv[1] == "--" {
+					return nil, 0, new_user_error(Requires_value, short, nil, "%s requires a value", short)
+				}
+				item.Value = argv[1]
+				consumed = 1
+			} else {
+				item.Value = left
+				left = ""
+			}
+		}
+		items = append(items, item)
+	}
+	return items, consumed, nil
+}
+
+// Result of matching a pattern against argv items.
+type Match struct {
+	Matched bool
+	Left    []*Item
+	// number of items consumed, also counted when the match fails: how far
+	// the pattern went
+	Consumed int
+	// positional items consumed
+	Consumed_positional int
+	// commands matched in order
+	Commands []string
+	// how many times each option was matched
+	Options map[*Option]int
+	// first required pattern which didn't match, and the items left at this point
+	Missing      *Pattern
+	Missing_left []*Item
+	// matched leaves and the items they consumed, in matching order
+	Leaves []*Pattern
+	Items  []*Item
+}
+
+// Trace_func receives each matching step: m is nil before matching p.
+type Trace_func func(depth int, p *Pattern, m *Match)
+
+// Match argv items against the pattern, docopt's algorithm.
+func (p *Pattern) Match(left []*Item) *Match {
+	return p.match(left, 0, nil)
+}
+
+// Match_trace is Match calling trace before and after matching each pattern node.
+func (p *Pattern) Match_trace(left []*Item, trace Trace_func) *Match {
+	return p.match(left, 0, trace)
+}
+
+func (p *Pattern) match(left []*Item, depth int, trace Trace_func) *Match {
+	if trace != nil {
+		trace(depth, p, nil)
+	}
+	m := p.match_node(left, depth, trace)
+	if trace != nil {
+		trace(depth, p, m)
+	}
+	return m
+}
+
+func (p *Pattern) match_node(left []*Item, depth int, trace Trace_func) *Match {
+	switch p.Type {
+	case Argument, Command, Option_leaf:
+		pos := p.single_match(left)
+		if pos < 0 {
+			return &Match{Matched: false, Left: left, Missing: p, Missing_left: left}
+		}
+		m := &Match{Matched: true, Consumed: 1, Leaves: []*Pattern{p}, Items: []*Item{left[pos]}}
+		m.Left = make([]*Item, 0, len(left)-1)
+		m.Left = append(m.Left, left[:pos]...)
+		m.Left = append(m.Left, left[pos+1:]...)
+		if p.Type != Option_leaf {
+			m.Consumed_positional = 1
+		}
+		if p.Type == Command {
+			m.Commands = []string{p.Name}
+		}
+		if p.Type == Option_leaf {
+			m.Options = map[*Option]int{p.Option: 1}
+		}
+		return m
+	case Required:
+		m := &Match{Matched: true, Left: left}
+		for _, c := range p.Children {
+			r := c.match(m.Left, depth+1, trace)
+			m.add(r)
+			if !r.Matched {
+				m.Matched = false
+				m.Left = left
+				m.Missing = r.Missing
+				m.Missing_left = r.Missing_left
+				return m
+			}
+			m.Left = r.Left
+		}
+		return m
+	case Optional, Options_shortcut:
+		m := &Match{Matched: true, Left: left}
+		for _, c := range p.Children {
+			r := c.match(m.Left, depth+1, trace)
+			if r.Matched {
+				m.add(r)
+				m.Left = r.Left
+			}
+		}
+		return m
+	case One_or_more:
+		m := &Match{Matched: false, Left: left}
+		for {
+			r := p.Children[0].match(m.Left, depth+1, trace)
+			if !r.Matched {
+				if !m.Matched {
+					m.Missing = r.Missing
+					m.Missing_left = r.Missing_left
+				}
+				break
+			}
+			m.Matched = true
+			m.add(r)
+			if len(r.Left) == len(m.Left) {
+				break
+			}
+			m.Left = r.Left
+		}
+		return m
+	case Either:
+		var best *Match
+		var closest *Match
+		for _, c := range p.Children {
+			r := c.match(left, depth+1, trace)
+			if r.Matched {
+				if best == nil || len(r.Left) < len(best.Left) {
+					best = r
+				}
+			} else if closest == nil || r.Consumed > closest.Consumed {
+				closest = r
+			}
+		}
+		if best != nil {
+			return best
+		}
+		if closest.Consumed == 0 {
+			// none went further, all alternatives are expected
+			closest.Missing = p
+		}
+		return closest
+	}
+	panic(fmt.Sprintf("Match(): unsupported pattern type: %v", p.Type))
+}
+
+func (m *Match) add(r *Match) {
+	m.Consumed += r.Consumed
+	m.Consumed_positional += r.Consumed_positional
+	m.Commands = append(m.Commands, r.Commands...)
+	m.Leaves = append(m.Leaves, r.Leaves...)
+	m.Items = append(m.Items, r.Items...)
+	for o, n := range r.Options {
+		if m.Options == nil {
+			m.Options = make(map[*Option]int)
+		}
+		m.Options[o] += n
+	}
+}
+
+// position of the item matched by a leaf pattern, -1 if not found
+func (p *Pattern) single_match(left []*Item) int {
+	for n, item := range left {
+		switch p.Type {
+		case Argument:
+			if item.Option == nil {
+				return n
+			}
+		case Command:
+			if item.Option == nil {
+				if item.Value == p.Name {
+					return n
+				}
+				// a command must be the first positional argument
+				return -1
+			}
+		case Option_leaf:
+			if item.Option == p.Option {
+				return n
+			}
+		}
+	}
+	return -1
+}
+
+// First_leaves returns the leaves which can start the pattern.
+func (p *Pattern) First_leaves() []*Pattern {
+	switch p.Type {
+	case Argument, Command, Option_leaf:
+		return []*Pattern{p}
+	case Either:
+		leaves := []*Pattern{}
+		for _, c := range p.Children {
+			leaves = append(leaves, c.First_leaves()...)
+		}
+		return leaves
+	}
+	// sequence: optional children don't stop the search
+	leaves := []*Pattern{}
+	for _, c := range p.Children {
+		leaves = append(leaves, c.First_leaves()...)
+		if c.Type != Optional && c.Type != Options_shortcut {
+			break
+		}
+	}
+	return leaves
+}
+
+// Usage_patterns returns one pattern per usage line.
+func (d *Doc) Usage_patterns() []*Pattern {
+	if len(d.Pattern.Children) == 1 && d.Pattern.Children[0].Type == Either {
+		return d.Pattern.Children[0].Children
+	}
+	return []*Pattern{d.Pattern}
+}
+
+// Explain returns why argv doesn't match any usage pattern, nil if argv matches.
+func (d *Doc) Explain(argv []string, options_first bool) error {
+	items, err := d.Parse_argv(argv, options_first)
+	if err != nil {
+		return err
+	}
+
+	// find the closest usage pattern, the first one wins on tie: commands
+	// weight more than arguments, which weight more than options
+	var best *Match
+	score := func(m *Match) int {
+		s := 2*m.Consumed_positional + len(m.Commands) + (m.Consumed - m.Consumed_positional)
+		if m.Matched {
+			s++
+		}
+		return s
+	}
+	// all missing patterns at the best score, for listing expected commands
+	missing := []*Pattern{}
+	for _, p := range d.Usage_patterns() {
+		m := p.Match(items)
+		if m.Matched && len(m.Left) == 0 {
+			return nil
+		}
+		if best == nil || score(m) > score(best) {
+			best = m
+			missing = []*Pattern{}
+		}
+		if score(m) == score(best) && !m.Matched {
+			missing = append(missing, m.Missing)
+		}
+	}
+
+	if best.Matched {
+		return explain_left(best)
+	}
+	return explain_missing(best, missing)
+}
+
+// explain items left after a successful match
+func explain_left(m *Match) error {
+	item := m.Left[0]
+	if item.Option == nil {
+		return new_user_error(Unexpected_argument, item.Value, nil, "Unexpected argument '%s'", item.Value)
+	}
+
+	// reports the option as given: -d or --debug
+	name := item.Option.Long
+	if !strings.HasPrefix(item.Token, "--") || name == "" {
+		name = item.Option.Short
+	}
+	if m.Options[item.Option] > 0 {
+		return new_user_error(Repeated_option, name, nil, "Option %s is given too many times", name)
+	}
+	if len(m.Commands) > 0 {
+		return new_user_error(Not_allowed, name, nil, "Option %s is not allowed with command '%s'",
+			name, strings.Join(m.Commands, " "))
+	}
+	return new_user_error(Not_allowed, name, nil, "Option %s is not allowed here", name)
+}
+
+// explain the first required pattern which didn't match
+func explain_missing(m *Match, missing []*Pattern) error {
+	leaves := []*Pattern{}
+	seen := make(map[string]bool)
+	for _, p := range missing {
+		for _, l := range p.First_leaves() {
+			if !seen[l.Name] {
+				seen[l.Name] = true
+				leaves = append(leaves, l)
+			}
+		}
+	}
+
+	names := make([]string, len(leaves))
+	commands := []string{}
+	has_argument := false
+	for i, l := range leaves {
+		names[i] = l.Name
+		switch l.Type {
+		case Command:
+			commands = append(commands, l.Name)
+		case Argument:
+			has_argument = true
+		}
+	}
+
+	if len(commands) > 0 && !has_argument {
+		for _, item := range m.Missing_left {
+			if item.Option == nil {
+				return new_user_error(Unknown_command, item.Value, commands, "Unknown command '%s'", item.Value).suggest()
+			}
+		}
+		return new_user_error(Missing_command, commands[0], commands, "Missing command %s", quote_or(commands))
+	}
+
+	l := leaves[0]
+	switch l.Type {
+	case Argument:
+		return new_user_error(Missing_argument, l.Name, names, "Missing required argument %s", l.Name)
+	case Option_leaf:
+		return new_user_error(Missing_option, l.Name, names, "Missing required option %s", l.Name)
+	}
+	return new_user_error(Missing_command, l.Name, names, "Missing command %s", quote_or(names))
+}
+
+// 'a', 'b' or 'c'
+func quote_or(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = "'" + n + "'"
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
@@ -0,0 +1,130 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for match.go
+//
+package grammar
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse_argv(t *testing.T) {
+	d, err := Parse_doc("Usage: prog [-v] [-o FILE] [--speed=<kn>] [--verbose] <x>...\n\nOptions:\n  -o FILE  Output.\n  -v  V.")
+	if err != nil {
+		t.Fatalf("Parse_doc error: %v", err)
+	}
+
+	tables := []struct {
+		argv   []string
+		expect []string
+	}{
+		{[]string{"a", "-vofile", "b"}, []string{"a", "-v", "-o=file", "b"}},
+		{[]string{"--sp", "5", "--verb"}, []string{"--speed=5", "--verbose"}},
+		{[]string{"-o", "f", "--", "-v"}, []string{"-o=f", "--", "-v"}},
+	}
+	for _, table := range tables {
+		items, err := d.Parse_argv(table.argv, false)
+		if err != nil {
+			t.Errorf("Parse_argv %v error: %v", table.argv, err)
+			continue
+		}
+		got := []string{}
+		for _, i := range items {
+			got = append(got, i.String())
+		}
+		if !reflect.DeepEqual(got, table.expect) {
+			t.Errorf("Parse_argv %v\ngot: %v\nwant: %v", table.argv, got, table.expect)
+		}
+	}
+
+	items, _ := d.Parse_argv([]string{"a", "-v"}, true)
+	if len(items) != 2 || items[1].Option != nil {
+		t.Errorf("Parse_argv options_first got: %v", items)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	d, err := Parse_doc(naval_fate)
+	if err != nil {
+		t.Fatalf("Parse_doc error: %v", err)
+	}
+
+	tables := []struct {
+		argv   []string
+		kind   Error_kind
+		msg    string
+		expect []string
+	}{
+		{[]string{"ship", "new", "--junk"}, Invalid_option, "Invalid option '--junk'", nil},
+		{[]string{"ship", "x", "move", "1", "2", "--speed"}, Requires_value, "--speed requires a value", nil},
+		{[]string{"--version=2"}, Unexpected_value, "--version doesn't take a value", nil},
+		{[]string{"shipp"}, Unknown_command, "Unknown command 'shipp', did you mean 'ship'?", []string{"ship", "mine"}},
+		{[]string{"ship", "x", "move", "1", "2", "--sped=5"}, Invalid_option, "Invalid option '--sped', did you mean '--speed'?", nil},
+		{[]string{}, Missing_command, "Missing command 'ship' or 'mine'", []string{"ship", "mine"}},
+		{[]string{"mine", "drop"}, Unknown_command, "Unknown command 'drop'", []string{"set", "remove"}},
+		{[]string{"ship", "new"}, Missing_argument, "Missing required argument <name>", []string{"<name>"}},
+		{[]string{"ship", "shoot", "1"}, Missing_argument, "Missing required argument <y>", []string{"<y>"}},
+		{[]string{"ship", "shoot", "1", "2", "3"}, Unexpected_argument, "Unexpected argument '3'", nil},
+		{[]string{"ship", "shoot", "1", "2", "--moored"}, Not_allowed, "Option --moored is not allowed with command 'ship shoot'", nil},
+		{[]string{"mine", "set", "1", "2", "--moored", "--moored"}, Repeated_option, "Option --moored is given too many times", nil},
+	}
+	for _, table := range tables {
+		err := d.Explain(table.argv, false)
+		if err == nil {
+			t.Errorf("Explain %v expecting error", table.argv)
+			continue
+		}
+		e := err.(*User_error)
+		if e.Kind != table.kind || e.Error() != table.msg {
+			t.Errorf("Explain %v\ngot: %d '%s'\nwant: %d '%s'", table.argv, e.Kind, e, table.kind, table.msg)
+		}
+		if table.expect != nil && !reflect.DeepEqual(e.Expected, table.expect) {
+			t.Errorf("Explain %v Expected\ngot: %v\nwant: %v", table.argv, e.Expected, table.expect)
+		}
+	}
+
+	for _, argv := range [][]string{{"ship", "new", "a", "b"}, {"mine", "set", "1", "2", "--drifting"}, {"--help"}} {
+		if err := d.Explain(argv, false); err != nil {
+			t.Errorf("Explain %v unexpected error: %v", argv, err)
+		}
+	}
+}
+
+func TestExplain_missing_option(t *testing.T) {
+	d, _ := Parse_doc("Usage: prog --speed=<kn> <x>")
+	err := d.Explain([]string{"a"}, false)
+	if err == nil || err.Error() != "Missing required option --speed" {
+		t.Errorf("Explain got: %v", err)
+	}
+	err = d.Explain([]string{"a", "-x"}, false)
+	if err == nil || err.Error() != "Invalid option '-x'" {
+		t.Errorf("Explain got: %v", err)
+	}
+}
+
+func TestMatch_trace(t *testing.T) {
+	d, _ := Parse_doc("Usage: prog go <x> [-v]")
+	items, _ := d.Parse_argv([]string{"-v", "go", "a"}, false)
+
+	steps := []string{}
+	m := d.Pattern.Match_trace(items, func(depth int, p *Pattern, m *Match) {
+		if m != nil && p.Is_leaf() {
+			steps = append(steps, p.Name)
+		}
+	})
+	if !m.Matched || len(m.Left) != 0 {
+		t.Fatalf("Match_trace expecting a full match got: %+v", m)
+	}
+	expect := []string{"go", "<x>", "-v"}
+	if !reflect.DeepEqual(steps, expect) {
+		t.Errorf("Match_trace steps\ngot: %v\nwant: %v", steps, expect)
+	}
+	tokens := []string{}
+	for _, i := range m.Items {
+		tokens = append(tokens, i.Token)
+	}
+	if !reflect.DeepEqual(tokens, []string{"go", "a", "-v"}) || len(m.Leaves) != 3 {
+		t.Errorf("Match_trace Items got: %v", tokens)
+	}
+}
@@ -0,0 +1,117 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// suggest.go: "did you mean" suggestions for mistyped commands and options.
+//
+package grammar
+
+import (
+	"sort"
+	"strings"
+)
+
+// Levenshtein returns the edit distance between a and b.
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// Typo_distance is Levenshtein() where swapping two adjacent letters is one
+// edit, as the common typo slwo for slow (optimal string alignment distance).
+func Typo_distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// d[i][j]: distance between ra[:i] and rb[:j]
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min3(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] && d[i-2][j-2]+1 < d[i][j] {
+				d[i][j] = d[i-2][j-2] + 1
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// Suggest returns the candidates close enough to word: the ones starting with
+// word first, then by Typo_distance(), up to 1 for words of 4 letters or less.
+// Leading dashes are ignored, so single letter options are never suggested:
+// any other letter would be as close.
+func Suggest(word string, candidates []string) []string {
+	w := strings.TrimLeft(word, "-")
+	max_distance := len(w)/3 + 1
+	if len(w) <= 4 {
+		max_distance = 1
+	}
+	if max_distance >= len(w) {
+		max_distance = len(w) - 1
+	}
+
+	type scored struct {
+		name     string
+		prefix   bool
+		distance int
+	}
+	found := []scored{}
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if seen[c] || c == word {
+			continue
+		}
+		seen[c] = true
+		name := strings.TrimLeft(c, "-")
+		prefix := len(w) > 1 && strings.HasPrefix(name, w)
+		dist := Typo_distance(w, name)
+		if prefix || dist <= max_distance {
+			found = append(found, scored{c, prefix, dist})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].prefix != found[j].prefix {
+			return found[i].prefix
+		}
+		return found[i].distance < found[j].distance
+	})
+
+	suggestions := []string{}
+	for _, s := range found {
+		suggestions = append(suggestions, s.name)
+	}
+	return suggestions
+}
@@ -0,0 +1,73 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for suggest.go
+//
+package grammar
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tables := []struct {
+		a, b   string
+		expect int
+	}{
+		{"", "", 0},
+		{"ship", "ship", 0},
+		{"shipp", "ship", 1},
+		{"sped", "speed", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+	}
+	for _, table := range tables {
+		if res := Levenshtein(table.a, table.b); res != table.expect {
+			t.Errorf("Levenshtein('%s', '%s') got: %d want: %d", table.a, table.b, res, table.expect)
+		}
+	}
+}
+
+func TestTypo_distance(t *testing.T) {
+	tables := []struct {
+		a, b   string
+		expect int
+	}{
+		{"", "", 0},
+		{"slwo", "slow", 1},
+		{"gen", "run", 2},
+		{"kitten", "sitting", 3},
+		{"ca", "abc", 3},
+	}
+	for _, table := range tables {
+		if res := Typo_distance(table.a, table.b); res != table.expect {
+			t.Errorf("Typo_distance('%s', '%s') got: %d want: %d", table.a, table.b, res, table.expect)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	tables := []struct {
+		word       string
+		candidates []string
+		expect     []string
+	}{
+		{"shipp", []string{"mine", "ship"}, []string{"ship"}},
+		{"--sped", []string{"--speed", "--moored", "--drifting"}, []string{"--speed"}},
+		{"--verbos", []string{"--verbose", "--version"}, []string{"--verbose", "--version"}},
+		{"xyz", []string{"ship", "mine"}, []string{}},
+		// prefix first, short words only at distance 1
+		{"gen", []string{"run", "generate", "man"}, []string{"generate"}},
+		{"slwo", []string{"fast", "slow"}, []string{"slow"}},
+		{"docs", []string{"doc", "dock", "debug"}, []string{"doc", "dock"}},
+		{"--verb", []string{"--version", "--verbose"}, []string{"--verbose"}},
+		// single letter: anything would be a suggestion
+		{"-x", []string{"-v", "-q"}, []string{}},
+	}
+	for _, table := range tables {
+		res := Suggest(table.word, table.candidates)
+		if !reflect.DeepEqual(res, table.expect) {
+			t.Errorf("Suggest('%s', %v)\ngot: %v\nwant: %v", table.word, table.candidates, res, table.expect)
+		}
+	}
+}
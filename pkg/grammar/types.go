@@ -0,0 +1,166 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// types.go: typed values annotations in option descriptions.
+//
+// Annotations are found in the description of an option taking an argument:
+//
+//   --speed=<kn>   Speed in knots [type: int] [default: 10].
+//   --mode=<m>     Mode [choices: fast slow].
+//   --input=<f>    Input file [type: path, exists].
+//   --name=<n>     Name [pattern: ^[a-z]+$].
+//   --token=<t>    API token [env: MYTOOL_TOKEN].
+//
+// They are an error on an option without argument, a flag or a counter.
+//
+// docopt's [default: ...] is greedy: it must be the last annotation of its line,
+// an annotation taken in by the default value is reported by Check_annotations().
+//
+package grammar
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// known [type: ...] values
+var Types = []string{"string", "int", "float", "path"}
+
+var re_env_name = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// annotations names, but default
+var annotation_names = []string{"type", "choices", "pattern", "env"}
+
+// annotation returns the value of [name: value] in text. Brackets must be
+// balanced inside value: [pattern: ^[a-z]+$]
+func annotation(text, name string) (string, bool) {
+	start := strings.Index(strings.ToLower(text), "["+name+":")
+	if start < 0 {
+		return "", false
+	}
+	value := text[start+len(name)+2:]
+	depth := 1
+	for i, c := range value {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return strings.TrimSpace(value[:i]), true
+			}
+		}
+	}
+	return "", false
+}
+
+func (o *Option) parse_annotations(text string) {
+	if value, found := annotation(text, "type"); found {
+		flags := strings.Split(value, ",")
+		o.Type = strings.TrimSpace(flags[0])
+		for _, f := range flags[1:] {
+			f = strings.TrimSpace(f)
+			if f == "exists" {
+				o.Exists = true
+			} else {
+				// reported by Check_annotations()
+				o.Type += "," + f
+			}
+		}
+	}
+	if value, found := annotation(text, "choices"); found {
+		o.Choices = strings.Fields(value)
+	}
+	if value, found := annotation(text, "pattern"); found {
+		o.Pattern = value
+	}
+	if value, found := annotation(text, "env"); found {
+		o.Env = value
+	}
+}
+
+// Has_annotations is true if the option's value must be checked by Convert().
+func (o *Option) Has_annotations() bool {
+	return o.Type != "" || o.Choices != nil || o.Pattern != ""
+}
+
+// Check_annotations reports invalid annotations: unknown type, bad regexp or
+// environment variable name, annotations of an option without argument, or
+// following [default: ...].
+func (o *Option) Check_annotations() error {
+	if o.Argcount == 0 && (o.Env != "" || o.Has_annotations()) {
+		return fmt.Errorf("%s: annotations need an option taking an argument, a flag or a counter has no value", o.Name())
+	}
+	if o.Has_default {
+		// taken in by the greedy [default: ...]
+		for _, name := range annotation_names {
+			if strings.Contains(strings.ToLower(o.Default), "["+name+":") {
+				return fmt.Errorf("%s: [%s: ...] must be before [default: ...], got the default '%s'", o.Name(), name, o.Default)
+			}
+		}
+	}
+	if o.Env != "" && !re_env_name.MatchString(o.Env) {
+		return fmt.Errorf("%s: invalid environment variable name '%s'", o.Name(), o.Env)
+	}
+	if o.Type != "" {
+		known := false
+		for _, t := range Types {
+			known = known || o.Type == t
+		}
+		if !known {
+			return fmt.Errorf("%s: unknown type '%s', known types: %s", o.Name(), o.Type, strings.Join(Types, " "))
+		}
+	}
+	if o.Exists && o.Type != "path" {
+		return fmt.Errorf("%s: exists is only supported for [type: path]", o.Name())
+	}
+	if o.Pattern != "" {
+		if _, err := regexp.Compile(o.Pattern); err != nil {
+			return fmt.Errorf("%s: invalid pattern: %v", o.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Convert checks value against the option's annotations and returns it typed:
+// int, float64 or string. Errors are *User_error.
+func (o *Option) Convert(value string) (interface{}, error) {
+	name := o.Name()
+	if o.Choices != nil {
+		found := false
+		for _, c := range o.Choices {
+			found = found || value == c
+		}
+		if !found {
+			return nil, new_user_error(Invalid_value, value, o.Choices,
+				"%s must be one of: %s, got '%s'", name, strings.Join(o.Choices, " "), value).suggest()
+		}
+	}
+	if o.Pattern != "" && !regexp.MustCompile(o.Pattern).MatchString(value) {
+		return nil, new_user_error(Invalid_value, value, nil, "%s must match %s, got '%s'", name, o.Pattern, value)
+	}
+
+	switch o.Type {
+	case "int":
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, new_user_error(Invalid_value, value, nil, "%s must be an int, got '%s'", name, value)
+		}
+		return i, nil
+	case "float":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, new_user_error(Invalid_value, value, nil, "%s must be a float, got '%s'", name, value)
+		}
+		return f, nil
+	case "path":
+		if o.Exists {
+			if _, err := os.Stat(value); err != nil {
+				return nil, new_user_error(Invalid_value, value, nil, "%s must be an existing path, got '%s'", name, value)
+			}
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,110 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for types.go
+//
+package grammar
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse_option_annotations(t *testing.T) {
+	tables := []struct {
+		input  string
+		expect Option
+	}{
+		{"--speed=<kn>  Speed [type: int] [default: 10].", Option{
+			Long: "--speed", Argcount: 1, Arg_name: "<kn>", Default: "10", Has_default: true,
+			Description: "Speed [type: int] [default: 10].", Described: true, Type: "int",
+		}},
+		{"-i FILE  Input\n   [TYPE: path, exists]", Option{
+			Short: "-i", Argcount: 1, Arg_name: "FILE",
+			Description: "Input [TYPE: path, exists]", Described: true, Type: "path", Exists: true,
+		}},
+		{"--mode=<m>  Mode [choices: fast  slow]", Option{
+			Long: "--mode", Argcount: 1, Arg_name: "<m>",
+			Description: "Mode [choices: fast slow]", Described: true, Choices: []string{"fast", "slow"},
+		}},
+		{"--name=<n>  Name [pattern: ^[a-z]+$]", Option{
+			Long: "--name", Argcount: 1, Arg_name: "<n>",
+			Description: "Name [pattern: ^[a-z]+$]", Described: true, Pattern: "^[a-z]+$",
+		}},
+		{"--token=<t>  API token [env: MYTOOL_TOKEN] [default: none]", Option{
+			Long: "--token", Argcount: 1, Arg_name: "<t>", Default: "none", Has_default: true,
+			Description: "API token [env: MYTOOL_TOKEN] [default: none]", Described: true, Env: "MYTOOL_TOKEN",
+		}},
+		// option without argument, reported by Check_annotations()
+		{"-v  Verbose [type: int]", Option{Short: "-v", Description: "Verbose [type: int]", Described: true, Type: "int"}},
+		{"-v, --verbose  [env: VERBOSE]", Option{Short: "-v", Long: "--verbose", Description: "[env: VERBOSE]", Described: true, Env: "VERBOSE"}},
+	}
+
+	for _, table := range tables {
+		o := Parse_option(table.input)
+		if !reflect.DeepEqual(*o, table.expect) {
+			t.Errorf("Parse_option for '%s'\ngot: '%+v'\nwant: '%+v'\n", table.input, *o, table.expect)
+		}
+	}
+}
+
+func TestCheck_annotations(t *testing.T) {
+	tables := []struct {
+		input string
+		valid bool
+	}{
+		{"--a=<x>  [type: float]", true},
+		{"--a=<x>  [type: integer]", false},
+		{"--a=<x>  [type: int, exists]", false},
+		{"--a=<x>  [type: path, writable]", false},
+		{"--a=<x>  [pattern: (a]", false},
+		{"--a=<x>  [env: A_TOKEN]", true},
+		{"--a=<x>  [env: A-TOKEN]", false},
+		{"-v, --verbose  [env: VERBOSE]", false},
+		{"-v  Verbose [choices: a b]", false},
+		{"-v  Verbose [default: 1]", true},
+		{"--a=<x>  [type: int] [default: 10]", true},
+		{"--a=<x>  [default: 10] [type: int]", false},
+		{"--a=<x>  [default: a] [choices: a b]", false},
+		{"--a=<x>  [default: a] [Pattern: ^a$]", false},
+		{"--a=<x>  [default: x] [env: A_TOKEN]", false},
+	}
+	for _, table := range tables {
+		err := Parse_option(table.input).Check_annotations()
+		if (err == nil) != table.valid {
+			t.Errorf("Check_annotations for '%s' got: %v", table.input, err)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tables := []struct {
+		input  string
+		value  string
+		expect interface{}
+		err    string
+	}{
+		{"--a=<x>  [type: int]", "42", 42, ""},
+		{"--a=<x>  [type: int]", "4.2", nil, "--a must be an int, got '4.2'"},
+		{"--a=<x>  [type: float]", "4.2", 4.2, ""},
+		{"--a=<x>  [type: float]", "x", nil, "--a must be a float, got 'x'"},
+		{"--a=<x>  [type: path, exists]", ".", ".", ""},
+		{"--a=<x>  [type: path, exists]", "/not/found", nil, "--a must be an existing path, got '/not/found'"},
+		{"--a=<x>  [type: path]", "/not/found", "/not/found", ""},
+		{"--a=<x>  [choices: red green]", "green", "green", ""},
+		{"--a=<x>  [choices: red green]", "grean", nil, "--a must be one of: red green, got 'grean', did you mean 'green'?"},
+		{"--a=<x>  [pattern: ^[a-z]+$] [type: string]", "abc", "abc", ""},
+		{"--a=<x>  [pattern: ^[a-z]+$]", "ab1", nil, "--a must match ^[a-z]+$, got 'ab1'"},
+	}
+	for _, table := range tables {
+		res, err := Parse_option(table.input).Convert(table.value)
+		if table.err != "" {
+			if err == nil || err.Error() != table.err || err.(*User_error).Kind != Invalid_value {
+				t.Errorf("Convert '%s' for '%s'\ngot error: '%v'\nwant: '%s'", table.value, table.input, err, table.err)
+			}
+			continue
+		}
+		if err != nil || res != table.expect {
+			t.Errorf("Convert '%s' for '%s'\ngot: %#v %v\nwant: %#v", table.value, table.input, res, err, table.expect)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// value.go: parsed values types, as docopt builds them.
+//
+package grammar
+
+import (
+	"strings"
+)
+
+// Keys returns the unique leaves of the pattern, by name, in pattern order.
+// They are the keys of docopt's parsed arguments.
+func (d *Doc) Keys() []*Pattern {
+	keys := []*Pattern{}
+	seen := make(map[string]bool)
+	for _, l := range d.Pattern.Leaves() {
+		if !seen[l.Name] {
+			seen[l.Name] = true
+			keys = append(keys, l)
+		}
+	}
+	return keys
+}
+
+// Repeating returns the names of the leaves which can be given more than once,
+// their value is a counter or a list. Same rule as docopt: a leaf is repeating
+// if it appears twice in one of the expanded alternatives of the pattern.
+func (d *Doc) Repeating() map[string]bool {
+	repeating := make(map[string]bool)
+	for _, alternative := range expand(d.Pattern) {
+		count := make(map[string]int)
+		for _, l := range alternative {
+			count[l.Name]++
+			if count[l.Name] > 1 {
+				repeating[l.Name] = true
+			}
+		}
+	}
+	return repeating
+}
+
+// expand the pattern into all its alternatives, as lists of leaves:
+// Either gives one alternative per child, One_or_more is expanded twice.
+func expand(p *Pattern) [][]*Pattern {
+	result := [][]*Pattern{}
+	groups := [][]*Pattern{{p}}
+	for len(groups) > 0 {
+		children := groups[0]
+		groups = groups[1:]
+
+		branch := -1
+		for i, c := range children {
+			if !c.Is_leaf() {
+				branch = i
+				break
+			}
+		}
+		if branch < 0 {
+			result = append(result, children)
+			continue
+		}
+
+		child := children[branch]
+		rest := make([]*Pattern, 0, len(children)-1)
+		rest = append(rest, children[:branch]...)
+		rest = append(rest, children[branch+1:]...)
+		switch child.Type {
+		case Either:
+			for _, c := range child.Children {
+				groups = append(groups, append([]*Pattern{c}, rest...))
+			}
+		case One_or_more:
+			group := append([]*Pattern{}, child.Children...)
+			group = append(group, child.Children...)
+			groups = append(groups, append(group, rest...))
+		default:
+			group := append([]*Pattern{}, child.Children...)
+			groups = append(groups, append(group, rest...))
+		}
+	}
+	return result
+}
+
+// Default_value returns the value of a leaf not found in argv, typed as
+// docopt does: bool, int for counters, string, []string for lists or nil.
+func (d *Doc) Default_value(leaf *Pattern, repeating map[string]bool) interface{} {
+	switch leaf.Type {
+	case Argument:
+		if repeating[leaf.Name] {
+			return []string{}
+		}
+		return nil
+	case Command:
+		if repeating[leaf.Name] {
+			return 0
+		}
+		return false
+	case Option_leaf:
+		o := leaf.Option
+		if o.Argcount == 0 {
+			if repeating[leaf.Name] {
+				return 0
+			}
+			return false
+		}
+		if repeating[leaf.Name] {
+			if o.Has_default {
+				return strings.Fields(o.Default)
+			}
+			return []string{}
+		}
+		if o.Has_default {
+			return o.Default
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for value.go
+//
+package grammar
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRepeating(t *testing.T) {
+	tables := []struct {
+		doc    string
+		expect map[string]bool
+	}{
+		{"Usage: prog <x>...", map[string]bool{"<x>": true}},
+		{"Usage: prog <x> <x>", map[string]bool{"<x>": true}},
+		{"Usage: prog (<x> | <y>)", map[string]bool{}},
+		{"Usage: prog go <d> [go <d>]", map[string]bool{"go": true, "<d>": true}},
+		{"Usage: prog [-v...] <x>\n  prog -v", map[string]bool{"-v": true}},
+	}
+	for _, table := range tables {
+		d, err := Parse_doc(table.doc)
+		if err != nil {
+			t.Fatalf("Parse_doc error: %v", err)
+		}
+		if res := d.Repeating(); !reflect.DeepEqual(res, table.expect) {
+			t.Errorf("Repeating for '%s'\ngot: %v\nwant: %v", table.doc, res, table.expect)
+		}
+	}
+}
+
+func TestDefault_value(t *testing.T) {
+	d, err := Parse_doc(`Usage: prog go [go] [-v -q -q] [--speed=<kn>] [--out=FILE] [-i <in>]... <x> [<y>...]
+
+Options:
+  --speed=<kn>  Speed [default: 10].
+  -i <in>       Input [default: a b].`)
+	if err != nil {
+		t.Fatalf("Parse_doc error: %v", err)
+	}
+
+	expect := map[string]interface{}{
+		"go":      0,
+		"-v":      false,
+		"-q":      0,
+		"--speed": "10",
+		"--out":   nil,
+		"-i":      []string{"a", "b"},
+		"<x>":     nil,
+		"<y>":     []string{},
+	}
+	repeating := d.Repeating()
+	names := []string{}
+	for _, k := range d.Keys() {
+		names = append(names, k.Name)
+		res := d.Default_value(k, repeating)
+		if !reflect.DeepEqual(res, expect[k.Name]) {
+			t.Errorf("Default_value for '%s'\ngot: %#v\nwant: %#v", k.Name, res, expect[k.Name])
+		}
+	}
+	expect_keys := []string{"go", "-v", "-q", "--speed", "--out", "-i", "<x>", "<y>"}
+	if !reflect.DeepEqual(names, expect_keys) {
+		t.Errorf("Keys\ngot: %v\nwant: %v", names, expect_keys)
+	}
+}
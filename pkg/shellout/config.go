@@ -0,0 +1,400 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// config.go: load options values from a JSON, TOML or INI config file.
+//
+// The config file is given by an option of the parsed usage, named with
+// docopts's --config-option:
+//
+//   docopts --config-option=--config -h "$usage" : "$@"
+//
+// Keys are option names, with or without dashes: "speed", "--speed" or "s".
+// Keys can also be put in a section named after the program, which overrides
+// the same option at the top level. Precedence is: argv, environment, config
+// file, then [default: ...].
+//
+package shellout
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/docopt/docopt-go"
+	"github.com/docopt/docopts/pkg/grammar"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Config_error is an error in the config file given by the user, reported
+// as a parse error.
+type Config_error struct {
+	File string
+	msg  string
+}
+
+func (e *Config_error) Error() string {
+	return fmt.Sprintf("config %s: %s", e.File, e.msg)
+}
+
+func config_error(file string, format string, a ...interface{}) *Config_error {
+	return &Config_error{File: file, msg: fmt.Sprintf(format, a...)}
+}
+
+// Read_config reads the config file, its format is found from the file
+// extension: .json, .toml, .ini, .cfg or .conf. Values are flattened:
+// sections or nested objects give dotted keys, values are bool, string or
+// []string.
+func Read_config(filename string) (map[string]interface{}, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, config_error(filename, "%v", err)
+	}
+	var values map[string]interface{}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		values, err = parse_json_config(content)
+	case ".toml":
+		values, err = parse_toml_config(content)
+	case ".ini", ".cfg", ".conf":
+		values, err = parse_ini_config(content)
+	default:
+		return nil, config_error(filename, "unknown format, expecting: .json .toml .ini .cfg .conf")
+	}
+	if err != nil {
+		return nil, config_error(filename, "%v", err)
+	}
+	return values, nil
+}
+
+func parse_json_config(content []byte) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	var object map[string]interface{}
+	if err := dec.Decode(&object); err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{})
+	return values, flatten_json(values, "", object)
+}
+
+func flatten_json(values map[string]interface{}, prefix string, object map[string]interface{}) error {
+	for k, v := range object {
+		key := prefix + k
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if err := flatten_json(values, key+".", v); err != nil {
+				return err
+			}
+		case []interface{}:
+			list := make([]string, len(v))
+			for i, e := range v {
+				s, ok := json_scalar(e)
+				if !ok {
+					return fmt.Errorf("%s: unsupported value in list: %v", key, e)
+				}
+				list[i] = s
+			}
+			values[key] = list
+		case bool:
+			values[key] = v
+		default:
+			s, ok := json_scalar(v)
+			if !ok {
+				return fmt.Errorf("%s: unsupported value: %v", key, v)
+			}
+			values[key] = s
+		}
+	}
+	return nil
+}
+
+func json_scalar(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+// INI: [section], key = value or key: value, ; and # comments. A repeated key
+// gives a list.
+func parse_ini_config(content []byte) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == ';' || line[0] == '#':
+			continue
+		case line[0] == '[' && line[len(line)-1] == ']':
+			section = strings.TrimSpace(line[1:len(line)-1]) + "."
+			continue
+		}
+		i := strings.IndexAny(line, "=:")
+		if i < 1 {
+			return nil, fmt.Errorf("line %d: expecting key = value: %s", n, line)
+		}
+		key := section + strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		switch previous := values[key].(type) {
+		case nil:
+			values[key] = value
+		case string:
+			values[key] = []string{previous, value}
+		case []string:
+			values[key] = append(previous, value)
+		}
+	}
+	return values, scanner.Err()
+}
+
+// TOML subset: [table], key = value with strings, numbers, booleans and
+// arrays of them on one line, # comments.
+func parse_toml_config(content []byte) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	table := ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			end := strings.Index(line, "]")
+			rest := ""
+			if end >= 0 {
+				rest = strings.TrimSpace(line[end+1:])
+			}
+			if end < 0 || rest != "" && rest[0] != '#' {
+				return nil, fmt.Errorf("line %d: invalid table: %s", n, line)
+			}
+			table = toml_key(line[1:end]) + "."
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("line %d: expecting key = value: %s", n, line)
+		}
+		key := table + toml_key(line[:i])
+		value, rest, err := toml_value(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %v", n, key, err)
+		}
+		if rest = strings.TrimSpace(rest); rest != "" && rest[0] != '#' {
+			return nil, fmt.Errorf("line %d: %s: unexpected '%s'", n, key, rest)
+		}
+		values[key] = value
+	}
+	return values, scanner.Err()
+}
+
+func toml_key(key string) string {
+	key = strings.TrimSpace(key)
+	if unquoted, err := strconv.Unquote(key); err == nil {
+		return unquoted
+	}
+	return strings.Trim(key, "'")
+}
+
+// toml_value parses the value at the beginning of s, returns it with the
+// remaining text.
+func toml_value(s string) (interface{}, string, error) {
+	if s == "" {
+		return nil, "", fmt.Errorf("missing value")
+	}
+	switch s[0] {
+	case '"':
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				value, err := strconv.Unquote(s[:i+1])
+				return value, s[i+1:], err
+			}
+		}
+		return nil, "", fmt.Errorf("unterminated string")
+	case '\'':
+		end := strings.Index(s[1:], "'")
+		if end < 0 {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	case '[':
+		list := []string{}
+		rest := strings.TrimSpace(s[1:])
+		for {
+			if rest != "" && rest[0] == ']' {
+				return list, rest[1:], nil
+			}
+			value, r, err := toml_value(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			switch v := value.(type) {
+			case string:
+				list = append(list, v)
+			case bool:
+				list = append(list, strconv.FormatBool(v))
+			default:
+				return nil, "", fmt.Errorf("unsupported value in array")
+			}
+			rest = strings.TrimSpace(r)
+			if rest != "" && rest[0] == ',' {
+				rest = strings.TrimSpace(rest[1:])
+			} else if rest == "" || rest[0] != ']' {
+				return nil, "", fmt.Errorf("unterminated array")
+			}
+		}
+	}
+	end := strings.IndexAny(s, " \t,]#")
+	if end < 0 {
+		end = len(s)
+	}
+	word := s[:end]
+	switch word {
+	case "true", "false":
+		return word == "true", s[end:], nil
+	}
+	if _, err := strconv.ParseFloat(strings.Replace(word, "_", "", -1), 64); err != nil {
+		return nil, "", fmt.Errorf("unsupported value '%s'", word)
+	}
+	return strings.Replace(word, "_", "", -1), s[end:], nil
+}
+
+// config_option_for finds the option of a config key: --speed, speed, s and
+// dry_run for --dry-run.
+func config_option_for(d *grammar.Doc, key string) *grammar.Option {
+	if strings.HasPrefix(key, "-") {
+		return d.Find_option(key)
+	}
+	candidates := []string{"--" + key, "--" + strings.Replace(key, "_", "-", -1)}
+	if len(key) == 1 {
+		candidates = []string{"-" + key}
+	}
+	for _, c := range candidates {
+		if o := d.Find_option(c); o != nil {
+			return o
+		}
+	}
+	return nil
+}
+
+// Config_merge reads the config file given by config_option, if any, and
+// fills the options whose source is still default or unset. sources are
+// updated. Errors in the file are *Config_error, a config_option not found
+// in the usage is a plain error.
+func Config_merge(args docopt.Opts, doc string, sources []*Option_source, config_option string) error {
+	d, err := grammar.Parse_doc(doc)
+	if err != nil {
+		return err
+	}
+	o := d.Find_option(config_option)
+	if o == nil || o.Argcount == 0 {
+		return fmt.Errorf("--config-option: '%s' is not an option taking an argument in USAGE", config_option)
+	}
+	filename, _ := args[o.Name()].(string)
+	if filename == "" {
+		return nil
+	}
+	values, err := Read_config(filename)
+	if err != nil {
+		return err
+	}
+
+	by_name := make(map[string]*Option_source)
+	for _, s := range sources {
+		by_name[s.Option.Name()] = s
+	}
+	section := d.Prog + "."
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	// the [<prog>] section first, as it takes precedence over the top level,
+	// then sorted for a stable first error
+	sort.Slice(keys, func(i, j int) bool {
+		in_i, in_j := strings.HasPrefix(keys[i], section), strings.HasPrefix(keys[j], section)
+		if in_i != in_j {
+			return in_i
+		}
+		return keys[i] < keys[j]
+	})
+	// option name => key which set it
+	set_by := make(map[string]string)
+	for _, key := range keys {
+		option := config_option_for(d, strings.TrimPrefix(key, section))
+		if option == nil || by_name[option.Name()] == nil || option == o {
+			return config_error(filename, "unknown key '%s'", key)
+		}
+		if prev_key, found := set_by[option.Name()]; found {
+			if strings.HasPrefix(prev_key, section) == strings.HasPrefix(key, section) {
+				return config_error(filename, "'%s' and '%s' both set %s", prev_key, key, option.Name())
+			}
+			// top level key overridden by the section
+			continue
+		}
+		set_by[option.Name()] = key
+		s := by_name[option.Name()]
+		if s.From != "default" && s.From != "unset" {
+			continue
+		}
+		value, err := config_value(args[option.Name()], values[key])
+		if err != nil {
+			return config_error(filename, "%s: %v", key, err)
+		}
+		args[option.Name()] = value
+		s.From = "config " + filename
+	}
+	return nil
+}
+
+// config_value converts a config value to the type of docopt's parsed value:
+// bool for flags, int for counters, []string for repeatable options.
+func config_value(current interface{}, value interface{}) (interface{}, error) {
+	switch current.(type) {
+	case bool:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		if s, ok := value.(string); ok {
+			switch strings.ToLower(s) {
+			case "true", "yes", "on", "1":
+				return true, nil
+			case "false", "no", "off", "0":
+				return false, nil
+			}
+		}
+		return nil, fmt.Errorf("expecting a boolean, got '%v'", value)
+	case int:
+		if s, ok := value.(string); ok {
+			if i, err := strconv.Atoi(s); err == nil {
+				return i, nil
+			}
+		}
+		return nil, fmt.Errorf("expecting a count, got '%v'", value)
+	case []string:
+		switch v := value.(type) {
+		case []string:
+			return v, nil
+		case string:
+			return strings.Fields(v), nil
+		}
+	default:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected value '%v'", value)
+}
@@ -0,0 +1,156 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for config.go
+//
+package shellout
+
+import (
+	"github.com/docopt/docopt-go"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRead_config(t *testing.T) {
+	expect := map[string]interface{}{
+		"speed": "12", "verbose": true, "prog.name": []string{"a", "b"},
+	}
+	tables := []struct {
+		ext     string
+		content string
+	}{
+		{".json", `{"speed": 12, "verbose": true, "prog": {"name": ["a", "b"]}}`},
+		{".toml", "# comment\nspeed = 1_2 # knots\nverbose = true\n\n[prog]\nname = [\"a\", 'b']\n"},
+	}
+	dir, err := ioutil.TempDir("", "docopts_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, table := range tables {
+		filename := filepath.Join(dir, "config"+table.ext)
+		ioutil.WriteFile(filename, []byte(table.content), 0644)
+		values, err := Read_config(filename)
+		if err != nil || !reflect.DeepEqual(values, expect) {
+			t.Errorf("Read_config %s\ngot: %#v %v\nwant: %#v", table.ext, values, err, expect)
+		}
+	}
+
+	// ini values are strings, repeated keys give a list
+	filename := filepath.Join(dir, "config.ini")
+	ioutil.WriteFile(filename, []byte("; comment\nspeed = 12\nverbose: yes\n[prog]\nname = a\nname = \"b\"\n"), 0644)
+	values, err := Read_config(filename)
+	expect["verbose"] = "yes"
+	if err != nil || !reflect.DeepEqual(values, expect) {
+		t.Errorf("Read_config .ini\ngot: %#v %v\nwant: %#v", values, err, expect)
+	}
+
+	for _, bad := range []string{"config.yaml", "not_found.json"} {
+		if _, err := Read_config(filepath.Join(dir, bad)); err == nil {
+			t.Errorf("Read_config expecting error for %s", bad)
+		}
+	}
+}
+
+func TestToml_value(t *testing.T) {
+	tables := []struct {
+		input  string
+		expect interface{}
+		rest   string
+	}{
+		{`"a \"b\"" # c`, `a "b"`, " # c"},
+		{`'c:\dir'`, `c:\dir`, ""},
+		{`-1.5e3,`, "-1.5e3", ","},
+		{`false`, false, ""},
+		{`[ 1, "two" , true ]`, []string{"1", "two", "true"}, ""},
+		{`[]`, []string{}, ""},
+	}
+	for _, table := range tables {
+		value, rest, err := toml_value(table.input)
+		if err != nil || !reflect.DeepEqual(value, table.expect) || rest != table.rest {
+			t.Errorf("toml_value(%s) got: %#v '%s' %v", table.input, value, rest, err)
+		}
+	}
+	for _, bad := range []string{`"open`, `[1, 2`, `pipo`, `[[1]]`} {
+		if _, _, err := toml_value(bad); err == nil {
+			t.Errorf("toml_value(%s) expecting error", bad)
+		}
+	}
+}
+
+func TestConfig_merge(t *testing.T) {
+	doc := `Usage: prog [-v...] [--dry-run] [--speed=<kn>] [--host=<h>] [-n <n>]... [--config=<f>]
+
+Options:
+  -v            Verbose.
+  --dry-run     Dry run.
+  --speed=<kn>  Speed [default: 10].
+  --host=<h>    Host [env: HOST].
+  -n <n>        Numbers.
+  --config=<f>  Config file.
+  --unused=<u>  Not in usage.`
+	dir, err := ioutil.TempDir("", "docopts_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "prog.toml")
+	ioutil.WriteFile(filename, []byte("v = 2\ndry_run = true\nspeed = 5\nhost = 'config'\n[prog]\nn = [1, 2]\n"), 0644)
+
+	argv := []string{"--config", filename, "--speed=7"}
+	args := docopt.Opts{"-v": 0, "--dry-run": false, "--speed": "7", "--host": nil, "-n": []string{}, "--config": filename}
+	env := map[string]string{"HOST": "env"}
+	sources, err := Env_fallback(args, doc, argv, false, func(name string) (string, bool) {
+		value, found := env[name]
+		return value, found
+	})
+	if err != nil {
+		t.Fatalf("Env_fallback error: %v", err)
+	}
+	if err := Config_merge(args, doc, sources, "--config"); err != nil {
+		t.Fatalf("Config_merge error: %v", err)
+	}
+	expect := docopt.Opts{"-v": 2, "--dry-run": true, "--speed": "7", "--host": "env", "-n": []string{"1", "2"}, "--config": filename}
+	if !reflect.DeepEqual(args, expect) {
+		t.Errorf("Config_merge got: %v\nwant: %v", args, expect)
+	}
+	if sources[0].From != "config "+filename || sources[2].From != "argv" || sources[3].From != "env HOST" {
+		t.Errorf("Config_merge wrong sources: %s, %s, %s", sources[0].From, sources[2].From, sources[3].From)
+	}
+
+	// the [prog] section overrides the top level, whichever key sorts first
+	for _, content := range []string{
+		"--speed = 1\ndry_run = false\n[prog]\nspeed = 2\ndry-run = true\n",
+		"speed = 1\n[prog]\n--speed = 2\ndry_run = true\n",
+	} {
+		ioutil.WriteFile(filename, []byte(content), 0644)
+		args = docopt.Opts{"-v": 0, "--dry-run": false, "--speed": "10", "--host": nil, "-n": []string{}, "--config": filename}
+		sources, _ = Env_fallback(args, doc, []string{"--config", filename}, false, func(string) (string, bool) { return "", false })
+		if err := Config_merge(args, doc, sources, "--config"); err != nil || args["--speed"] != "2" || args["--dry-run"] != true {
+			t.Errorf("Config_merge section precedence for '%s' got: %v %v", content, args, err)
+		}
+	}
+
+	// no config file given
+	args = docopt.Opts{"-v": 0, "--config": nil}
+	if err := Config_merge(args, doc, sources, "--config"); err != nil || args["-v"] != 0 {
+		t.Errorf("Config_merge without file got: %v %v", args, err)
+	}
+
+	// errors
+	if err := Config_merge(args, doc, sources, "--dry-run"); err == nil {
+		t.Errorf("Config_merge expecting error for an option without argument")
+	}
+	for _, content := range []string{"unused = 1\n", "dry_run = 'maybe'\n", "other.speed = 1\n", "config = 'x'\n",
+		"speed = 1\n--speed = 2\n", "[prog]\nspeed = 1\ndry_run = true\n--speed = 2\n"} {
+		ioutil.WriteFile(filename, []byte(content), 0644)
+		args = docopt.Opts{"-v": 0, "--dry-run": false, "--speed": "10", "--host": nil, "-n": []string{}, "--config": filename}
+		sources, _ = Env_fallback(args, doc, []string{"--config", filename}, false, func(string) (string, bool) { return "", false })
+		err := Config_merge(args, doc, sources, "--config")
+		if _, ok := err.(*Config_error); !ok {
+			t.Errorf("Config_merge expecting Config_error for '%s', got: %v", content, err)
+		}
+	}
+}
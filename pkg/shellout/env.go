@@ -0,0 +1,78 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// env.go: environment variables fallback for options not given in argv.
+//
+// An option taking an argument can name an environment variable in its
+// description:
+//
+//   --token=<t>  API token [env: MYTOOL_TOKEN].
+//
+// Precedence is: argv, then the environment, then [default: ...].
+//
+package shellout
+
+import (
+	"github.com/docopt/docopt-go"
+	"github.com/docopt/docopts/pkg/grammar"
+	"strings"
+)
+
+// Where an option's value comes from, as displayed by --debug.
+type Option_source struct {
+	Option *grammar.Option
+	// argv, env NAME, config FILE, default or unset
+	From string
+}
+
+// Env_fallback fills options not given in argv from their [env: NAME]
+// variable, overriding docopt's default. lookup is os.LookupEnv, an empty
+// variable counts as set. Sources of all options found in args are returned.
+func Env_fallback(args docopt.Opts, doc string, argv []string, options_first bool,
+	lookup func(string) (string, bool)) ([]*Option_source, error) {
+	d, err := grammar.Parse_doc(doc)
+	if err != nil {
+		return nil, err
+	}
+	items, err := d.Parse_argv(argv, options_first)
+	if err != nil {
+		return nil, err
+	}
+	given := make(map[string]bool)
+	for _, item := range items {
+		if item.Option != nil {
+			given[item.Option.Name()] = true
+		}
+	}
+
+	sources := []*Option_source{}
+	for _, o := range d.Options {
+		name := o.Name()
+		current, in_args := args[name]
+		if !in_args {
+			// not in usage patterns
+			continue
+		}
+		if err := o.Check_annotations(); err != nil {
+			return nil, err
+		}
+		s := &Option_source{Option: o, From: "unset"}
+		sources = append(sources, s)
+		if given[name] {
+			s.From = "argv"
+			continue
+		}
+		if value, found := lookup(o.Env); o.Env != "" && found {
+			s.From = "env " + o.Env
+			if _, repeating := current.([]string); repeating {
+				args[name] = strings.Fields(value)
+			} else {
+				args[name] = value
+			}
+			continue
+		}
+		if o.Has_default {
+			s.From = "default"
+		}
+	}
+	return sources, nil
+}
@@ -0,0 +1,66 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for env.go
+//
+package shellout
+
+import (
+	"github.com/docopt/docopt-go"
+	"reflect"
+	"testing"
+)
+
+func TestEnv_fallback(t *testing.T) {
+	doc := `Usage: prog [--token=<t>] [--user=<u>] [--host=<h>] [-n <n>]...
+
+Options:
+  --token=<t>  API token [env: MYTOOL_TOKEN] [default: none].
+  --user=<u>   User [env: MYTOOL_USER] [default: nobody].
+  --host=<h>   Host [env: MYTOOL_HOST].
+  -n <n>       Numbers [env: MYTOOL_NUMS].
+  --port=<p>   Not in usage [env: MYTOOL_PORT].`
+	env := map[string]string{"MYTOOL_TOKEN": "secret", "MYTOOL_USER": "bob", "MYTOOL_NUMS": "1 2", "MYTOOL_PORT": "22"}
+	lookup := func(name string) (string, bool) {
+		value, found := env[name]
+		return value, found
+	}
+
+	// as parsed by docopt
+	args := docopt.Opts{"--token": "none", "--user": "alice", "--host": nil, "-n": []string{}}
+	sources, err := Env_fallback(args, doc, []string{"--user", "alice"}, false, lookup)
+	if err != nil {
+		t.Fatalf("Env_fallback error: %v", err)
+	}
+	expect := docopt.Opts{"--token": "secret", "--user": "alice", "--host": nil, "-n": []string{"1", "2"}}
+	if !reflect.DeepEqual(args, expect) {
+		t.Errorf("Env_fallback got: %v\nwant: %v", args, expect)
+	}
+	from := []string{}
+	for _, s := range sources {
+		from = append(from, s.Option.Name()+": "+s.From)
+	}
+	expect_from := []string{"--token: env MYTOOL_TOKEN", "--user: argv", "--host: unset", "-n: env MYTOOL_NUMS"}
+	if !reflect.DeepEqual(from, expect_from) {
+		t.Errorf("Env_fallback sources got: %v\nwant: %v", from, expect_from)
+	}
+
+	// default is kept without variable
+	env = map[string]string{}
+	args = docopt.Opts{"--token": "none", "--user": "nobody", "--host": nil, "-n": []string{}}
+	sources, err = Env_fallback(args, doc, []string{}, false, lookup)
+	if err != nil || args["--token"] != "none" || sources[0].From != "default" {
+		t.Errorf("Env_fallback without env got: %v %v", args, err)
+	}
+
+	// wrong variable name
+	_, err = Env_fallback(docopt.Opts{"--token": nil}, "Usage: prog [--token=<t>]\n\nOptions:\n  --token=<t>  [env: MY-TOKEN]", []string{}, false, lookup)
+	if err == nil {
+		t.Errorf("Env_fallback expecting error for invalid variable name")
+	}
+
+	// flags have no value to read from the environment
+	_, err = Env_fallback(docopt.Opts{"--verbose": false}, "Usage: prog [-v]\n\nOptions:\n  -v, --verbose  [env: VERBOSE]", []string{}, false, lookup)
+	if err == nil {
+		t.Errorf("Env_fallback expecting error for [env: ...] on a flag")
+	}
+}
@@ -0,0 +1,63 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// environ.go: parsed arguments as environment variables, for programs
+// started by docopts instead of evaluating shell code.
+//
+package shellout
+
+import (
+	"fmt"
+	"github.com/docopt/docopt-go"
+	"strings"
+)
+
+// Environ returns the parsed arguments as "NAME=value" environment entries,
+// names are mangled as global variables. Values are the shell output's
+// without quoting: true or false, numbers, strings, empty for unset values
+// and repeatable arguments joined with Export_separator. Names of the sh
+// special variables, like PATH, are an error.
+func (d *Docopts) Environ(args docopt.Opts) ([]string, error) {
+	names := New_name_mangler(d)
+	names.Reserved = sh_reserved
+	env := make([]string, 0, len(args))
+	for _, key := range Sort_args_keys(args) {
+		name, err := names.Name(key)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			continue
+		}
+		env = append(env, fmt.Sprintf("%s=%s", name, To_environ(args[key], d.Export_separator)))
+	}
+	return env, nil
+}
+
+// Convert a parsed value to an environment variable value.
+func To_environ(v interface{}, separator string) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, separator)
+	case nil:
+		return ""
+	}
+	return To_bash(v)
+}
+
+// Merge_environ returns environ with the entries of env added, replacing the
+// variables of the same name.
+func Merge_environ(environ []string, env []string) []string {
+	replaced := make(map[string]bool)
+	for _, e := range env {
+		replaced[strings.SplitN(e, "=", 2)[0]] = true
+	}
+	merged := make([]string, 0, len(environ)+len(env))
+	for _, e := range environ {
+		if !replaced[strings.SplitN(e, "=", 2)[0]] {
+			merged = append(merged, e)
+		}
+	}
+	return append(merged, env...)
+}
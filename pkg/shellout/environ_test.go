@@ -0,0 +1,43 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for environ.go
+//
+package shellout
+
+import (
+	"github.com/docopt/docopt-go"
+	"reflect"
+	"testing"
+)
+
+func TestEnviron(t *testing.T) {
+	args := docopt.Opts{"<file>": []string{"a", "b c"}, "--speed": 2, "--name": nil, "-v": true, "--": false, "<msg>": "it's"}
+	d := &Docopts{Mangle_key: true, Export_separator: ":"}
+	env, err := d.Environ(args)
+	expect := []string{"name=", "speed=2", "v=true", "file=a:b c", "msg=it's"}
+	if err != nil || !reflect.DeepEqual(env, expect) {
+		t.Errorf("Environ got: %v %#v\nwant: %#v", err, env, expect)
+	}
+
+	d = &Docopts{Mangle_key: true, Global_prefix: "OPT", Export_separator: ","}
+	env, err = d.Environ(docopt.Opts{"<file>": []string{"a", "b"}, "--": true})
+	expect = []string{"OPT___=true", "OPT_file=a,b"}
+	if err != nil || !reflect.DeepEqual(env, expect) {
+		t.Errorf("Environ with prefix got: %v %#v\nwant: %#v", err, env, expect)
+	}
+
+	d = &Docopts{Mangle_key: true}
+	for _, bad := range []docopt.Opts{{"<PATH>": "x"}, {"-": true}, {"--long-option": true, "<long-option>": "x"}} {
+		if env, err := d.Environ(bad); err == nil {
+			t.Errorf("Environ expecting error for %v, got: %v", bad, env)
+		}
+	}
+}
+
+func TestMerge_environ(t *testing.T) {
+	merged := Merge_environ([]string{"HOME=/root", "OPT_x=old", "PATH=/bin"}, []string{"OPT_x=new", "OPT_y="})
+	expect := []string{"HOME=/root", "PATH=/bin", "OPT_x=new", "OPT_y="}
+	if !reflect.DeepEqual(merged, expect) {
+		t.Errorf("Merge_environ got: %#v\nwant: %#v", merged, expect)
+	}
+}
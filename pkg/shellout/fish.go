@@ -0,0 +1,105 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// fish.go: fish shell output, selected with --shell=fish.
+//
+// Variables are set with set -g, or set -l with --function, repeatable
+// arguments are lists and unset values are empty lists. fish has no
+// associative arrays, only global mode is available.
+//
+package shellout
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// fish special variables, a mangled name must not overwrite them
+var fish_reserved = map[string]bool{
+	"argv": true, "status": true, "pipestatus": true, "history": true,
+	"version": true, "hostname": true, "fish_pid": true, "last_pid": true,
+	"umask": true, "PWD": true, "SHLVL": true, "CMD_DURATION": true,
+	"COLUMNS": true, "LINES": true, "PATH": true, "HOME": true, "USER": true,
+	"IFS": true, "EUID": true, "FISH_VERSION": true, "status_generation": true,
+}
+
+// Fishquote quotes s inside fish single quotes, where only \ and ' are
+// escaped.
+func Fishquote(s string) string {
+	return strings.Replace(strings.Replace(s, `\`, `\\`, -1), `'`, `\'`, -1)
+}
+
+// Convert a parsed value to the arguments of fish's set: strings are quoted,
+// lists give one argument per element, nil an empty list.
+func To_fish(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("'%s'", Fishquote(v))
+	case []string:
+		quoted := make([]string, len(v))
+		for i, e := range v {
+			quoted[i] = To_fish(e)
+		}
+		return strings.Join(quoted, " ")
+	case nil:
+		return ""
+	}
+	return To_bash(v)
+}
+
+type fish_global_format struct {
+	d     *Docopts
+	names *Name_mangler
+	buf   bytes.Buffer
+}
+
+func (f *fish_global_format) Begin(w io.Writer) error {
+	return nil
+}
+
+func (f *fish_global_format) Emit_key(w io.Writer, key string, value interface{}) error {
+	name, err := f.names.Name(key)
+	if err != nil || name == "" {
+		return err
+	}
+	scope := "-g"
+	if f.d.Local {
+		scope = "-l"
+	}
+	fmt.Fprintf(&f.buf, "set %s %s", scope, name)
+	if v := To_fish(value); v != "" {
+		fmt.Fprintf(&f.buf, " %s", v)
+	}
+	f.buf.WriteString("\n")
+	return nil
+}
+
+func (f *fish_global_format) Emit_array(w io.Writer, key string, values []string) error {
+	return f.Emit_key(w, key, values)
+}
+
+// buffered as bash-global, nothing is outputed on mangling error
+func (f *fish_global_format) End(w io.Writer) error {
+	_, err := f.buf.WriteTo(w)
+	return err
+}
+
+// fish's echo doesn't interpret backslashes without -e
+func (f *fish_global_format) Error(w io.Writer, err error, usage string) error {
+	fmt.Fprintf(w, "echo '%s' >&2; %s\n", Fishquote(fmt.Sprintf("error: %s\n%s", err, usage)), f.d.Get_exit_code(64))
+	return nil
+}
+
+func (f *fish_global_format) Help(w io.Writer, message string) error {
+	fmt.Fprintf(w, "echo '%s'; %s\n", Fishquote(message), f.d.Get_exit_code(0))
+	return nil
+}
+
+func init() {
+	Register_format("fish-global", func(d *Docopts) OutputFormatter {
+		names := New_name_mangler(d)
+		names.Reserved = fish_reserved
+		return &fish_global_format{d: d, names: names}
+	})
+}
@@ -0,0 +1,71 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for fish.go
+//
+package shellout
+
+import (
+	"bytes"
+	"errors"
+	"github.com/docopt/docopt-go"
+	"testing"
+)
+
+func TestFishquote(t *testing.T) {
+	tables := []struct {
+		s      string
+		expect string
+	}{
+		{"plain", "plain"},
+		{"it's", `it\'s`},
+		{`a\b`, `a\\b`},
+		{`\'`, `\\\'`},
+		{"$HOME (x) *", "$HOME (x) *"},
+	}
+	for _, table := range tables {
+		if got := Fishquote(table.s); got != table.expect {
+			t.Errorf("Fishquote(%q) got: %q want: %q", table.s, got, table.expect)
+		}
+	}
+}
+
+func TestFish_global_format(t *testing.T) {
+	args := docopt.Opts{"--speed": 2, "<file>": []string{"a", "it's"}, "--name": nil, "-v": true, "<dir>": []string{}}
+	tables := []struct {
+		d      *Docopts
+		expect string
+	}{
+		{&Docopts{Shell: "fish", Mangle_key: true},
+			"set -g name\nset -g speed 2\nset -g v true\nset -g dir\nset -g file 'a' 'it\\'s'\n"},
+		{&Docopts{Shell: "fish", Mangle_key: true, Global_prefix: "ARGS", Local: true},
+			"set -l ARGS_name\nset -l ARGS_speed 2\nset -l ARGS_v true\nset -l ARGS_dir\nset -l ARGS_file 'a' 'it\\'s'\n"},
+	}
+	var out bytes.Buffer
+	for _, table := range tables {
+		if err := table.d.Print_result(&out, &Result{Args: args}); err != nil {
+			t.Errorf("fish format %s error: %v", table.d.Format_name(), err)
+		}
+		if out.String() != table.expect {
+			t.Errorf("fish format %s\ngot: '%s'\nwant: '%s'", table.d.Format_name(), out.String(), table.expect)
+		}
+		out.Reset()
+	}
+
+	// special variables aren't overwritten
+	d := &Docopts{Shell: "fish", Mangle_key: true}
+	if err := d.Print_result(&out, &Result{Args: docopt.Opts{"<status>": "x"}}); err == nil || out.Len() != 0 {
+		t.Errorf("fish-global expecting error for <status>, got: %v '%s'", err, out.String())
+	}
+	out.Reset()
+
+	d = &Docopts{Shell: "fish", Mangle_key: true, Exit_function: true}
+	d.Print_result(&out, &Result{Error: errors.New(`bad \n`), Usage: "Usage: prog"})
+	if expect := "echo 'error: bad \\\\n\nUsage: prog' >&2; return 64\n"; out.String() != expect {
+		t.Errorf("fish error got: '%s'\nwant: '%s'", out.String(), expect)
+	}
+	out.Reset()
+	d.Print_result(&out, &Result{Message: "it's help"})
+	if expect := "echo 'it\\'s help'; return 0\n"; out.String() != expect {
+		t.Errorf("fish help got: '%s'\nwant: '%s'", out.String(), expect)
+	}
+}
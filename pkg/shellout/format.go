@@ -0,0 +1,394 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// format.go: output formats of the parsed arguments, selected by name.
+//
+// Each format implements OutputFormatter and registers itself from an init()
+// function with Register_format(). Format() drives a formatter over a Result.
+//
+package shellout
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/docopt/docopt-go"
+	"io"
+	"sort"
+	"strings"
+)
+
+// An OutputFormatter renders a Result: Begin, one Emit_key or Emit_array per
+// key in sorted order, then End. Error or Help replace them all.
+type OutputFormatter interface {
+	Begin(w io.Writer) error
+	// value is bool, int, float64, string or nil
+	Emit_key(w io.Writer, key string, value interface{}) error
+	// values of a repeatable argument
+	Emit_array(w io.Writer, key string, values []string) error
+	End(w io.Writer) error
+	// user error: argv doesn't match the usage
+	Error(w io.Writer, err error, usage string) error
+	// --help or --version message
+	Help(w io.Writer, message string) error
+}
+
+var formats = make(map[string]func(d *Docopts) OutputFormatter)
+
+// Register_format makes a format available by name, new_format builds the
+// formatter for the Docopts output options.
+func Register_format(name string, new_format func(d *Docopts) OutputFormatter) {
+	if _, seen := formats[name]; seen {
+		panic(fmt.Sprintf("Register_format(): format already registered: '%s'", name))
+	}
+	formats[name] = new_format
+}
+
+func Format_names() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New_format returns the formatter registered as name.
+func New_format(name string, d *Docopts) (OutputFormatter, error) {
+	new_format, found := formats[name]
+	if !found {
+		return nil, fmt.Errorf("unknown format '%s', known formats: %s", name, strings.Join(Format_names(), " "))
+	}
+	return new_format(d), nil
+}
+
+// Format_name is Docopts.Format if given, or the format selected by the
+// legacy options: --json, --nameref, -A, --no-mangle or -G, for Docopts.Shell.
+func (d *Docopts) Format_name() string {
+	shell := d.Shell
+	if shell == "" {
+		shell = "bash"
+	}
+	switch {
+	case d.Format != "":
+		return d.Format
+	case d.Json:
+		return "json"
+	case d.Nameref != "":
+		return shell + "-nameref"
+	case d.Assoc != "":
+		return shell + "-assoc"
+	case !d.Mangle_key:
+		return "no-mangle"
+	}
+	return shell + "-global"
+}
+
+// Format outputs the Result with the formatter f.
+func Format(w io.Writer, f OutputFormatter, r *Result) error {
+	switch {
+	case r.Error != nil:
+		return f.Error(w, r.Error, r.Usage)
+	case r.Message != "":
+		return f.Help(w, r.Message)
+	}
+	return Format_args(w, f, r.Args)
+}
+
+// Format_args outputs the parsed arguments with the formatter f.
+func Format_args(w io.Writer, f OutputFormatter, args docopt.Opts) error {
+	if err := f.Begin(w); err != nil {
+		return err
+	}
+	for _, key := range Sort_args_keys(args) {
+		var err error
+		if values, is_array := args[key].([]string); is_array {
+			err = f.Emit_array(w, key, values)
+		} else {
+			err = f.Emit_key(w, key, args[key])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return f.End(w)
+}
+
+// Name_mangler gives each key its variable name with Docopts.Name_mangle(),
+// and reports keys mangled to the same name. Shared by the formats
+// outputting one variable per key.
+type Name_mangler struct {
+	Docopts *Docopts
+	// names of the shell's special variables, which must not be overwritten
+	Reserved map[string]bool
+	// mangled name => key
+	seen map[string]string
+}
+
+func New_name_mangler(d *Docopts) *Name_mangler {
+	return &Name_mangler{Docopts: d, seen: make(map[string]string)}
+}
+
+// Name returns the variable name for key, empty if the key is skipped:
+// double-dash can't be mangled without Global_prefix. Keys are kept verbatim
+// if Docopts.Mangle_key is false.
+func (m *Name_mangler) Name(key string) (string, error) {
+	name := key
+	if m.Docopts.Mangle_key {
+		if key == "--" && m.Docopts.Global_prefix == "" {
+			// skip double-dash that can't be mangled #52
+			// so double-dash is not printed
+			// but still parsed by docopts
+			return "", nil
+		}
+		var err error
+		name, err = m.Docopts.Name_mangle(key)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if err := m.Claim(name, key); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// Claim records an extra variable name output for key, failing as Name() if
+// it is reserved or already used.
+func (m *Name_mangler) Claim(name string, key string) error {
+	if m.Reserved[name] {
+		return fmt.Errorf("%s: mangled name '%s' is a special shell variable, use -G <prefix>", key, name)
+	}
+
+	// test if already present in the map
+	if prev_key, seen := m.seen[name]; seen {
+		return fmt.Errorf("%s: two or more elements have identically mangled names", prev_key)
+	}
+	m.seen[name] = key
+	return nil
+}
+
+// bash 4+ associative array: -A <name>
+type bash_assoc_format struct {
+	d *Docopts
+	// names of the indexed arrays, if Docopts.Arrays is set
+	arrays *Name_mangler
+}
+
+func (f *bash_assoc_format) Begin(w io.Writer) error {
+	if !IsBashIdentifier(f.d.Assoc) {
+		return fmt.Errorf("-A: not a valid Bash identifier: '%s'", f.d.Assoc)
+	}
+	if f.d.Output_declare {
+		fmt.Fprintf(w, "%s -A %s\n", f.d.Declare_keyword(), f.d.Assoc)
+	}
+	if f.d.Arrays != "" {
+		f.arrays = New_name_mangler(f.d)
+	}
+	return nil
+}
+
+func (f *bash_assoc_format) Emit_key(w io.Writer, key string, value interface{}) error {
+	fmt.Fprintf(w, "%s['%s']=%s\n", f.d.Assoc, Shellquote(key), To_bash(value))
+	return nil
+}
+
+// Reuse python's fake nested Bash arrays for repeatable arguments with values.
+// The structure is:
+// bash_assoc[key,#]=length
+// bash_assoc[key,i]=value
+// 'i' is an integer from 0 to length-1
+// length can be 0, for empty array
+func (f *bash_assoc_format) Emit_array(w io.Writer, key string, values []string) error {
+	for index, v := range values {
+		fmt.Fprintf(w, "%s['%s,%d']=%s\n", f.d.Assoc, Shellquote(key), index, To_bash(v))
+	}
+	// size of the array
+	fmt.Fprintf(w, "%s['%s,#']=%d\n", f.d.Assoc, Shellquote(key), len(values))
+	if f.arrays != nil {
+		return f.emit_indexed_array(w, key, values)
+	}
+	return nil
+}
+
+// Real bash indexed array for a repeatable argument, named by expanding the
+// Docopts.Arrays template.
+func (f *bash_assoc_format) emit_indexed_array(w io.Writer, key string, values []string) error {
+	name, err := f.d.Array_name(key)
+	if err != nil {
+		return err
+	}
+	if err := f.arrays.Claim(name, key); err != nil {
+		return err
+	}
+	if f.d.Output_declare {
+		fmt.Fprintf(w, "%s -a ", f.d.Declare_keyword())
+	}
+	fmt.Fprintf(w, "%s=%s\n", name, To_bash(values))
+	return nil
+}
+
+func (f *bash_assoc_format) End(w io.Writer) error {
+	return nil
+}
+
+func (f *bash_assoc_format) Error(w io.Writer, err error, usage string) error {
+	f.d.Print_bash_error(w, err, usage)
+	return nil
+}
+
+func (f *bash_assoc_format) Help(w io.Writer, message string) error {
+	f.d.Print_bash_message(w, message)
+	return nil
+}
+
+// bash 4.3+ nameref: the associative array referenced by Docopts.Nameref,
+// declared by the calling function with 'local -n <ref>=<array>'. The array
+// can be local to any calling function, it is made a global associative
+// array if undeclared.
+type bash_nameref_format struct {
+	bash_assoc_format
+}
+
+func new_bash_nameref_format(d *Docopts) *bash_nameref_format {
+	nameref := *d
+	nameref.Assoc = d.Nameref
+	return &bash_nameref_format{bash_assoc_format{d: &nameref}}
+}
+
+func (f *bash_nameref_format) Begin(w io.Writer) error {
+	ref := f.d.Assoc
+	if !IsBashIdentifier(ref) {
+		return fmt.Errorf("--nameref: not a valid Bash identifier: '%s'", ref)
+	}
+	fmt.Fprintf(w, "[[ $(declare -p \"${!%s}\" 2>/dev/null) == 'declare -A'* ]] || declare -gA \"${!%s}\"\n", ref, ref)
+	fmt.Fprintf(w, "%s=()\n", ref)
+	return nil
+}
+
+// Status returned by the --nameref output after displaying --help or
+// --version, distinct from a successful parse, 0, and a usage error, 64: the
+// calling function can tell the array is left empty and stop.
+const Nameref_help_status = 100
+
+func (f *bash_nameref_format) Help(w io.Writer, message string) error {
+	fmt.Fprintf(w, "echo '%s'\nreturn %d\n", Shellquote(message), Nameref_help_status)
+	return nil
+}
+
+// Bash 3.2 compatible global variables, also used for --no-mangle. The
+// output is buffered, nothing is outputed on mangling error.
+type bash_global_format struct {
+	bash_assoc_format
+	names *Name_mangler
+	buf   bytes.Buffer
+}
+
+func new_bash_global_format(d *Docopts) *bash_global_format {
+	return &bash_global_format{bash_assoc_format: bash_assoc_format{d: d}, names: New_name_mangler(d)}
+}
+
+func (f *bash_global_format) Begin(w io.Writer) error {
+	return nil
+}
+
+func (f *bash_global_format) Emit_key(w io.Writer, key string, value interface{}) error {
+	name, err := f.names.Name(key)
+	if err != nil || name == "" {
+		return err
+	}
+	values, is_array := value.([]string)
+	if f.d.Export && is_array {
+		// arrays can't be exported
+		value = strings.Join(values, f.d.Export_separator)
+		is_array = false
+	}
+	if !f.d.Mangle_key {
+		fmt.Fprintf(&f.buf, "%s=%s\n", name, To_bash(value))
+		return nil
+	}
+	switch {
+	case f.d.Local:
+		f.buf.WriteString("local ")
+		if f.d.Readonly {
+			f.buf.WriteString("-r ")
+		}
+		if f.d.Export {
+			f.buf.WriteString("-x ")
+		}
+	case f.d.Export:
+		// declare would make it local inside a function
+		f.buf.WriteString("export ")
+	case f.d.Readonly && is_array:
+		f.buf.WriteString("readonly -a ")
+	case f.d.Readonly:
+		f.buf.WriteString("readonly ")
+	}
+	fmt.Fprintf(&f.buf, "%s=%s", name, To_bash(value))
+	if f.d.Export && f.d.Readonly && !f.d.Local {
+		fmt.Fprintf(&f.buf, "; readonly %s", name)
+	}
+	f.buf.WriteString("\n")
+	return nil
+}
+
+func (f *bash_global_format) Emit_array(w io.Writer, key string, values []string) error {
+	return f.Emit_key(w, key, values)
+}
+
+func (f *bash_global_format) End(w io.Writer) error {
+	_, err := f.buf.WriteTo(w)
+	return err
+}
+
+// single line JSON object
+type json_format struct {
+	d    *Docopts
+	args docopt.Opts
+}
+
+func (f *json_format) Begin(w io.Writer) error {
+	f.args = make(docopt.Opts)
+	return nil
+}
+
+func (f *json_format) Emit_key(w io.Writer, key string, value interface{}) error {
+	f.args[key] = value
+	return nil
+}
+
+func (f *json_format) Emit_array(w io.Writer, key string, values []string) error {
+	f.args[key] = values
+	return nil
+}
+
+func (f *json_format) End(w io.Writer) error {
+	return write_json(w, f.args)
+}
+
+func (f *json_format) Error(w io.Writer, err error, usage string) error {
+	return f.d.Print_json_error(w, err, usage)
+}
+
+func (f *json_format) Help(w io.Writer, message string) error {
+	return f.d.Print_json_message(w, message)
+}
+
+func init() {
+	Register_format("bash-assoc", func(d *Docopts) OutputFormatter {
+		return &bash_assoc_format{d: d}
+	})
+	Register_format("bash-nameref", func(d *Docopts) OutputFormatter {
+		return new_bash_nameref_format(d)
+	})
+	Register_format("bash-global", func(d *Docopts) OutputFormatter {
+		return new_bash_global_format(d)
+	})
+	Register_format("no-mangle", func(d *Docopts) OutputFormatter {
+		no_mangle := *d
+		no_mangle.Mangle_key = false
+		return new_bash_global_format(&no_mangle)
+	})
+	Register_format("json", func(d *Docopts) OutputFormatter {
+		return &json_format{d: d}
+	})
+}
@@ -0,0 +1,155 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for format.go
+//
+package shellout
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/docopt/docopt-go"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// records formatter calls
+type trace_format struct {
+	calls []string
+}
+
+func (f *trace_format) Begin(w io.Writer) error {
+	f.calls = append(f.calls, "begin")
+	return nil
+}
+
+func (f *trace_format) Emit_key(w io.Writer, key string, value interface{}) error {
+	f.calls = append(f.calls, fmt.Sprintf("key %s=%v", key, value))
+	return nil
+}
+
+func (f *trace_format) Emit_array(w io.Writer, key string, values []string) error {
+	f.calls = append(f.calls, fmt.Sprintf("array %s=%v", key, values))
+	return nil
+}
+
+func (f *trace_format) End(w io.Writer) error {
+	f.calls = append(f.calls, "end")
+	return nil
+}
+
+func (f *trace_format) Error(w io.Writer, err error, usage string) error {
+	f.calls = append(f.calls, "error "+err.Error())
+	return nil
+}
+
+func (f *trace_format) Help(w io.Writer, message string) error {
+	f.calls = append(f.calls, "help "+message)
+	return nil
+}
+
+func TestFormat(t *testing.T) {
+	var out bytes.Buffer
+	tables := []struct {
+		r      *Result
+		expect []string
+	}{
+		{&Result{Args: docopt.Opts{"-v": true, "<file>": []string{"a"}, "--speed": nil}},
+			[]string{"begin", "key --speed=<nil>", "key -v=true", "array <file>=[a]", "end"}},
+		{&Result{Message: "help"}, []string{"help help"}},
+		{&Result{Error: errors.New("bad")}, []string{"error bad"}},
+	}
+	for _, table := range tables {
+		f := &trace_format{}
+		if err := Format(&out, f, table.r); err != nil || !reflect.DeepEqual(f.calls, table.expect) {
+			t.Errorf("Format got: %v %v\nwant: %v", f.calls, err, table.expect)
+		}
+	}
+}
+
+func TestRegister_format(t *testing.T) {
+	Register_format("test-trace", func(d *Docopts) OutputFormatter { return &trace_format{} })
+	defer delete(formats, "test-trace")
+
+	d := &Docopts{Format: "test-trace"}
+	f, err := New_format(d.Format_name(), d)
+	if _, ok := f.(*trace_format); err != nil || !ok {
+		t.Errorf("New_format got: %v %v", f, err)
+	}
+	if _, err := New_format("not-found", d); err == nil {
+		t.Errorf("New_format expecting error for unknown format")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Register_format expecting panic on duplicate")
+		}
+	}()
+	Register_format("json", nil)
+}
+
+func TestFormat_name(t *testing.T) {
+	tables := []struct {
+		d      *Docopts
+		expect string
+	}{
+		{&Docopts{Mangle_key: true}, "bash-global"},
+		{&Docopts{Mangle_key: true, Global_prefix: "ARGS"}, "bash-global"},
+		{&Docopts{}, "no-mangle"},
+		{&Docopts{Mangle_key: true, Assoc: "args"}, "bash-assoc"},
+		{&Docopts{Mangle_key: true, Assoc: "args", Json: true}, "json"},
+		{&Docopts{Mangle_key: true, Assoc: "args", Format: "no-mangle"}, "no-mangle"},
+		{&Docopts{Mangle_key: true, Assoc: "args", Nameref: "ref"}, "bash-nameref"},
+	}
+	for _, table := range tables {
+		if res := table.d.Format_name(); res != table.expect {
+			t.Errorf("Format_name for %+v got: %s, want: %s", table.d, res, table.expect)
+		}
+	}
+}
+
+func TestBash_nameref_format(t *testing.T) {
+	var out bytes.Buffer
+	d := &Docopts{Mangle_key: true, Nameref: "ref", Exit_function: true}
+	args := docopt.Opts{"<file>": []string{"a"}, "-v": true}
+	if err := d.Print_result(&out, &Result{Args: args}); err != nil {
+		t.Errorf("bash-nameref error: %v", err)
+	}
+	expect := `[[ $(declare -p "${!ref}" 2>/dev/null) == 'declare -A'* ]] || declare -gA "${!ref}"` + "\n" +
+		"ref=()\nref['-v']=true\nref['<file>,0']='a'\nref['<file>,#']=1\n"
+	if out.String() != expect {
+		t.Errorf("bash-nameref\ngot: '%s'\nwant: '%s'", out.String(), expect)
+	}
+	out.Reset()
+
+	d.Nameref = "not-valid"
+	if err := d.Print_result(&out, &Result{Args: args}); err == nil {
+		t.Errorf("bash-nameref expecting error for '%s', got: '%s'", d.Nameref, out.String())
+	}
+	out.Reset()
+	d.Print_result(&out, &Result{Message: "help"})
+	if expect := "echo 'help'\nreturn 100\n"; out.String() != expect {
+		t.Errorf("bash-nameref help got: '%s'", out.String())
+	}
+}
+
+func TestName_mangler(t *testing.T) {
+	m := New_name_mangler(&Docopts{Mangle_key: true})
+	for key, expect := range map[string]string{"--long-option": "long_option", "--": "", "<file>": "file"} {
+		if name, err := m.Name(key); err != nil || name != expect {
+			t.Errorf("Name_mangler.Name(%s) got: '%s' %v, want: '%s'", key, name, err, expect)
+		}
+	}
+	if _, err := m.Name("<long-option>"); err == nil {
+		t.Errorf("Name_mangler.Name expecting error on collision")
+	}
+
+	// no output at all on error
+	var out bytes.Buffer
+	f, _ := New_format("bash-global", &Docopts{Mangle_key: true})
+	err := Format_args(&out, f, docopt.Opts{"--a-b": true, "<a_b>": "x", "-c": true})
+	if err == nil || out.Len() != 0 {
+		t.Errorf("bash-global expecting error and no output, got: %v '%s'", err, out.String())
+	}
+}
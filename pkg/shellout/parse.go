@@ -0,0 +1,134 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// parse.go: parses argv with a docopt usage into a Result.
+//
+package shellout
+
+import (
+	"github.com/docopt/docopt-go"
+	"github.com/docopt/docopts/pkg/grammar"
+	"os"
+)
+
+// Parser holds the parsing behavior, the same as docopts's command line options.
+type Parser struct {
+	// version message for --version, not handled if empty
+	Version string
+	// disallow interspersing options and positional arguments
+	Options_first bool
+	// don't handle --help and --version specially
+	No_help bool
+	// the option of the usage giving a config file, see Config_merge()
+	Config_option string
+	// lookup of [env: NAME] variables, os.LookupEnv if nil
+	Lookup_env func(string) (string, bool)
+}
+
+// The outcome of parsing argv, only one of Args, Message or Error is set.
+type Result struct {
+	// parsed arguments, as docopt returns them, typed values converted
+	Args docopt.Opts
+	// --help or --version was given: the message to display
+	Message string
+	// bad user input: argv doesn't match the usage, a typed value or the
+	// config file is wrong
+	Error error
+	// the usage displayed with Error
+	Usage string
+	// where each option's value comes from, also set with Error on typed values
+	Sources []*Option_source
+}
+
+// Parse parses argv with the usage doc. Errors are only returned for a wrong
+// doc, user errors are part of the Result.
+func (p *Parser) Parse(doc string, argv []string) (*Result, error) {
+	r := &Result{}
+	if argv == nil {
+		// docopt would parse os.Args
+		argv = []string{}
+	}
+	parser := &docopt.Parser{
+		OptionsFirst:  p.Options_first,
+		SkipHelpFlags: p.No_help,
+		HelpHandler: func(err error, usage string) {
+			if err == nil {
+				// --help or --version found and No_help is false
+				r.Message = usage
+				return
+			}
+			r.Error, r.Usage = err, usage
+			// docopt's generic error is replaced by a named error explaining why
+			// argv doesn't match doc: unknown command, missing argument...
+			if g, gerr := grammar.Parse_doc(doc); gerr == nil {
+				if user_error := g.Explain(argv, p.Options_first); user_error != nil {
+					r.Error, r.Usage = user_error, g.Usage_section
+				}
+			}
+		},
+	}
+	args, err := parser.ParseArgs(doc, argv, p.Version)
+	if r.Message != "" || r.Error != nil {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := p.Lookup_env
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	r.Sources, err = Env_fallback(args, doc, argv, p.Options_first, lookup)
+	if err != nil {
+		return nil, err
+	}
+	usage := grammar.Parse_section("usage:", doc)[0]
+	if p.Config_option != "" {
+		err = Config_merge(args, doc, r.Sources, p.Config_option)
+		if _, config_error := err.(*Config_error); config_error {
+			r.Error, r.Usage = err, usage
+			return r, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	err = Validate_args(args, grammar.Parse_options(doc))
+	if _, user_error := err.(*grammar.User_error); user_error {
+		r.Error, r.Usage = err, usage
+		return r, nil
+	} else if err != nil {
+		return nil, err
+	}
+	r.Args = args
+	return r, nil
+}
+
+// Check parsed values against the typed values annotations found in options
+// descriptions: [type: int], [choices: a b]... Values are converted to their
+// type, repeatable options are checked but kept as strings. A wrong annotation
+// is reported as a plain error, a wrong value as a *grammar.User_error.
+func Validate_args(args docopt.Opts, options []*grammar.Option) error {
+	for _, o := range options {
+		if !o.Has_annotations() {
+			continue
+		}
+		if err := o.Check_annotations(); err != nil {
+			return err
+		}
+		switch value := args[o.Name()].(type) {
+		case string:
+			typed, err := o.Convert(value)
+			if err != nil {
+				return err
+			}
+			args[o.Name()] = typed
+		case []string:
+			for _, v := range value {
+				if _, err := o.Convert(v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for parse.go
+//
+package shellout
+
+import (
+	"github.com/docopt/docopt-go"
+	"github.com/docopt/docopts/pkg/grammar"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc := `Naval Fate.
+
+Usage: naval_fate ship <name> [--speed=<kn>]
+       naval_fate --version
+
+Options:
+  --speed=<kn>  Speed [type: int] [env: NAVAL_SPEED] [default: 10].
+  --version     Show version.`
+	no_env := func(string) (string, bool) { return "", false }
+	p := &Parser{Version: "1.0", Lookup_env: no_env}
+
+	r, err := p.Parse(doc, []string{"ship", "boat"})
+	expect := docopt.Opts{"ship": true, "<name>": "boat", "--speed": 10, "--version": false}
+	if err != nil || !reflect.DeepEqual(r.Args, expect) {
+		t.Errorf("Parse got: %v %v\nwant: %v", r.Args, err, expect)
+	}
+	if len(r.Sources) != 2 || r.Sources[0].From != "default" {
+		t.Errorf("Parse wrong sources: %v", r.Sources)
+	}
+
+	p.Lookup_env = func(name string) (string, bool) { return "20", name == "NAVAL_SPEED" }
+	r, err = p.Parse(doc, []string{"ship", "boat"})
+	if err != nil || r.Args["--speed"] != 20 {
+		t.Errorf("Parse with env got: %v %v", r.Args, err)
+	}
+
+	r, err = p.Parse(doc, []string{"--version"})
+	if err != nil || r.Message != "1.0" || r.Args != nil || r.Error != nil {
+		t.Errorf("Parse --version got: %+v %v", r, err)
+	}
+	r, err = p.Parse(doc, []string{"--help"})
+	if err != nil || r.Message != doc {
+		t.Errorf("Parse --help got: %+v %v", r, err)
+	}
+	p.No_help = true
+	r, err = p.Parse(doc, []string{"--help"})
+	if err != nil || r.Error == nil {
+		t.Errorf("Parse --help with No_help got: %+v %v", r, err)
+	}
+
+	// named errors
+	tables := []struct {
+		argv   []string
+		expect string
+	}{
+		{[]string{"shipp", "boat"}, "Unknown command 'shipp', did you mean 'ship'?"},
+		{[]string{"ship", "boat", "--speed=fast"}, "--speed must be an int, got 'fast'"},
+	}
+	for _, table := range tables {
+		r, err = p.Parse(doc, table.argv)
+		if err != nil || r.Error == nil || r.Error.Error() != table.expect || r.Args != nil {
+			t.Errorf("Parse %v got: %+v %v\nwant: %s", table.argv, r, err, table.expect)
+			continue
+		}
+		if r.Usage != "Usage: naval_fate ship <name> [--speed=<kn>]\n       naval_fate --version" {
+			t.Errorf("Parse %v usage got: '%s'", table.argv, r.Usage)
+		}
+	}
+
+	// wrong doc
+	if _, err = p.Parse("no usage", nil); err == nil {
+		t.Errorf("Parse expecting error for a doc without usage")
+	}
+	if _, err = p.Parse("Usage: prog [--x=<x>]\n\nOptions:\n  --x=<x>  [type: integer]", nil); err == nil {
+		t.Errorf("Parse expecting error for a wrong annotation")
+	}
+}
+
+func TestValidate_args(t *testing.T) {
+	options := grammar.Parse_options(`Options:
+  --speed=<kn>  Speed [type: int] [default: 10].
+  --mode=<m>    Mode [choices: fast slow].
+  -i <in>       Inputs [pattern: ^[a-z]+$].`)
+
+	args := docopt.Opts{"--speed": "12", "--mode": nil, "-i": []string{"a", "b"}}
+	if err := Validate_args(args, options); err != nil {
+		t.Errorf("Validate_args error: %v", err)
+	}
+
+	// a flag has no value to check
+	flag := grammar.Parse_options("Options:\n  -v  Verbose [type: int].")
+	if err := Validate_args(docopt.Opts{"-v": true}, flag); err == nil {
+		t.Errorf("Validate_args expecting error for an annotated flag")
+	}
+	if args["--speed"] != 12 || args["--mode"] != nil {
+		t.Errorf("Validate_args converted got: %v", args)
+	}
+
+	tables := []struct {
+		args   docopt.Opts
+		expect string
+	}{
+		{docopt.Opts{"--speed": "x"}, "--speed must be an int, got 'x'"},
+		{docopt.Opts{"--mode": "slo"}, "--mode must be one of: fast slow, got 'slo', did you mean 'slow'?"},
+		{docopt.Opts{"-i": []string{"a", "B"}}, "-i must match ^[a-z]+$, got 'B'"},
+	}
+	for _, table := range tables {
+		err := Validate_args(table.args, options)
+		if _, ok := err.(*grammar.User_error); !ok || err.Error() != table.expect {
+			t.Errorf("Validate_args for %v\ngot: '%v'\nwant: '%s'", table.args, err, table.expect)
+		}
+	}
+
+	// wrong annotation
+	options = grammar.Parse_options("Options:\n  --speed=<kn>  Speed [type: integer].")
+	err := Validate_args(docopt.Opts{"--speed": "1"}, options)
+	if _, ok := err.(*grammar.User_error); err == nil || ok {
+		t.Errorf("Validate_args expecting annotation error, got: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// sh.go: POSIX sh output for dash or busybox ash, selected with --shell=sh.
+//
+// There are no arrays in POSIX sh: a repeatable argument is a counter
+// <name>_COUNT and one variable <name>_<i> per value. Messages are displayed
+// with printf, dash's echo interprets backslashes.
+//
+package shellout
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// POSIX special variables, a mangled name must not overwrite them
+var sh_reserved = map[string]bool{
+	"PATH": true, "HOME": true, "IFS": true, "PWD": true, "OLDPWD": true,
+	"PS1": true, "PS2": true, "PS4": true, "ENV": true, "CDPATH": true,
+	"OPTIND": true, "OPTARG": true, "LINENO": true, "PPID": true,
+	"MAIL": true, "MAILPATH": true, "MAILCHECK": true,
+}
+
+func sh_printf(w io.Writer, message string, stderr bool) {
+	redirect := ""
+	if stderr {
+		redirect = " >&2"
+	}
+	fmt.Fprintf(w, "printf '%%s\\n' '%s'%s\n", Shellquote(message), redirect)
+}
+
+type sh_global_format struct {
+	d     *Docopts
+	names *Name_mangler
+	buf   bytes.Buffer
+}
+
+func (f *sh_global_format) Begin(w io.Writer) error {
+	return nil
+}
+
+// local isn't POSIX, but dash and busybox ash have it
+func (f *sh_global_format) assign(name string, value string) {
+	if f.d.Local {
+		f.buf.WriteString("local ")
+	}
+	fmt.Fprintf(&f.buf, "%s=%s\n", name, value)
+}
+
+func (f *sh_global_format) Emit_key(w io.Writer, key string, value interface{}) error {
+	name, err := f.names.Name(key)
+	if err != nil || name == "" {
+		return err
+	}
+	f.assign(name, To_bash(value))
+	return nil
+}
+
+// <name>_COUNT=length and <name>_<i>=value, 'i' from 0 to length-1. The
+// generated names are checked for collisions too.
+func (f *sh_global_format) Emit_array(w io.Writer, key string, values []string) error {
+	name, err := f.names.Name(key)
+	if err != nil || name == "" {
+		return err
+	}
+	count := name + "_COUNT"
+	if err := f.names.Claim(count, key); err != nil {
+		return err
+	}
+	f.assign(count, fmt.Sprintf("%d", len(values)))
+	for index, v := range values {
+		element := fmt.Sprintf("%s_%d", name, index)
+		if err := f.names.Claim(element, key); err != nil {
+			return err
+		}
+		f.assign(element, To_bash(v))
+	}
+	return nil
+}
+
+// buffered as bash-global, nothing is outputed on mangling error
+func (f *sh_global_format) End(w io.Writer) error {
+	_, err := f.buf.WriteTo(w)
+	return err
+}
+
+func (f *sh_global_format) Error(w io.Writer, err error, usage string) error {
+	sh_printf(w, fmt.Sprintf("error: %s\n%s", err, usage), true)
+	fmt.Fprintln(w, f.d.Get_exit_code(64))
+	return nil
+}
+
+func (f *sh_global_format) Help(w io.Writer, message string) error {
+	sh_printf(w, message, false)
+	fmt.Fprintln(w, f.d.Get_exit_code(0))
+	return nil
+}
+
+func init() {
+	Register_format("sh-global", func(d *Docopts) OutputFormatter {
+		names := New_name_mangler(d)
+		names.Reserved = sh_reserved
+		return &sh_global_format{d: d, names: names}
+	})
+}
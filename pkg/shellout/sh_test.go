@@ -0,0 +1,62 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for sh.go
+//
+package shellout
+
+import (
+	"bytes"
+	"errors"
+	"github.com/docopt/docopt-go"
+	"testing"
+)
+
+func TestSh_global_format(t *testing.T) {
+	args := docopt.Opts{"--speed": 2, "<file>": []string{"a", "it's"}, "--name": nil, "-v": true, "<dir>": []string{}}
+	tables := []struct {
+		d      *Docopts
+		expect string
+	}{
+		{&Docopts{Shell: "sh", Mangle_key: true},
+			"name=\nspeed=2\nv=true\ndir_COUNT=0\nfile_COUNT=2\nfile_0='a'\nfile_1='it'\\''s'\n"},
+		{&Docopts{Shell: "sh", Mangle_key: true, Global_prefix: "ARGS", Local: true},
+			"local ARGS_name=\nlocal ARGS_speed=2\nlocal ARGS_v=true\nlocal ARGS_dir_COUNT=0\n" +
+				"local ARGS_file_COUNT=2\nlocal ARGS_file_0='a'\nlocal ARGS_file_1='it'\\''s'\n"},
+	}
+	var out bytes.Buffer
+	for _, table := range tables {
+		if err := table.d.Print_result(&out, &Result{Args: args}); err != nil {
+			t.Errorf("sh format %s error: %v", table.d.Format_name(), err)
+		}
+		if out.String() != table.expect {
+			t.Errorf("sh format %s\ngot: '%s'\nwant: '%s'", table.d.Format_name(), out.String(), table.expect)
+		}
+		out.Reset()
+	}
+
+	// generated names collide with other keys, in both orders
+	d := &Docopts{Shell: "sh", Mangle_key: true}
+	for _, collide := range []string{"<file_0>", "--file-COUNT"} {
+		a := docopt.Opts{"<file>": []string{"a"}, collide: "x"}
+		if err := d.Print_result(&out, &Result{Args: a}); err == nil || out.Len() != 0 {
+			t.Errorf("sh-global expecting collision for %s, got: %v '%s'", collide, err, out.String())
+		}
+		out.Reset()
+	}
+	if err := d.Print_result(&out, &Result{Args: docopt.Opts{"<IFS>": "x"}}); err == nil {
+		t.Errorf("sh-global expecting error for <IFS>")
+	}
+	out.Reset()
+
+	// messages with printf, dash's echo interprets backslashes
+	d = &Docopts{Shell: "sh", Mangle_key: true, Exit_function: true}
+	d.Print_result(&out, &Result{Error: errors.New(`bad \n`), Usage: "Usage: prog"})
+	if expect := "printf '%s\\n' 'error: bad \\n\nUsage: prog' >&2\nreturn 64\n"; out.String() != expect {
+		t.Errorf("sh error got: '%s'\nwant: '%s'", out.String(), expect)
+	}
+	out.Reset()
+	d.Print_result(&out, &Result{Message: "it's help"})
+	if expect := "printf '%s\\n' 'it'\\''s help'\nreturn 0\n"; out.String() != expect {
+		t.Errorf("sh help got: '%s'\nwant: '%s'", out.String(), expect)
+	}
+}
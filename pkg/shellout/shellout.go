@@ -0,0 +1,285 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// shellout parses a command line with a docopt usage and outputs the result as
+// shell code to be evaluated, the same way the docopts command line does.
+//
+// Go programs can generate docopts's bash snippets without running the binary:
+//
+//   p := &shellout.Parser{Version: "1.0"}
+//   r, err := p.Parse(usage, os.Args[1:])
+//   if err == nil {
+//       d := &shellout.Docopts{Mangle_key: true, Output_declare: true}
+//       err = d.Print_result(w, r)
+//   }
+//
+// Nothing exits: errors are returned, bad user input is part of the Result.
+//
+package shellout
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/docopt/docopt-go"
+	"github.com/docopt/docopts/pkg/grammar"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Store global behavior to avoid passing many optional arguments to methods.
+type Docopts struct {
+	Global_prefix  string
+	Mangle_key     bool
+	Output_declare bool
+	// output 'return' instead of 'exit', see: Get_exit_code()
+	Exit_function bool
+	// output 'local' variables for use inside a shell function
+	Local bool
+	// global variables are exported, arrays are joined with Export_separator
+	Export           bool
+	Export_separator string
+	// global variables are readonly
+	Readonly bool
+	// the associative array name for Print_bash_args, globals if empty
+	Assoc string
+	// the bash 4.3+ nameref to the associative array to fill, see format.go
+	Nameref string
+	// also output an indexed array per repeatable argument with Assoc, named
+	// by this template, see: Array_name()
+	Arrays string
+	// output a JSON object instead of shell code
+	Json bool
+	// output format name, see format.go, Format_name() if empty
+	Format string
+	// shell of the output format selected by Format_name(): bash, zsh, fish
+	// or sh, bash if empty
+	Shell string
+}
+
+func Sort_args_keys(args docopt.Opts) []string {
+	keys_list := make([]string, len(args))
+	i := 0
+	for k, _ := range args {
+		keys_list[i] = k
+		i++
+	}
+	sort.Strings(keys_list)
+	return keys_list
+}
+
+// Print_result outputs the parsed arguments, the help or version message or
+// the user error, with the format selected by Format_name().
+func (d *Docopts) Print_result(w io.Writer, r *Result) error {
+	f, err := New_format(d.Format_name(), d)
+	if err != nil {
+		return err
+	}
+	return Format(w, f, r)
+}
+
+// output bash 4+ compatible assoc array, suitable for eval.
+func (d *Docopts) Print_bash_args(w io.Writer, bash_assoc string, args docopt.Opts) error {
+	assoc := *d
+	assoc.Assoc = bash_assoc
+	return Format_args(w, &bash_assoc_format{d: &assoc}, args)
+}
+
+// Check if a value is an array
+func IsArray(rt reflect.Type) bool {
+	if rt == nil {
+		return false
+	}
+	switch rt.Kind() {
+	case reflect.Slice:
+		return true
+	case reflect.Array:
+		return true
+	default:
+		return false
+	}
+}
+
+func Shellquote(s string) string {
+	return strings.Replace(s, "'", `'\''`, -1)
+}
+
+func IsBashIdentifier(s string) bool {
+	identifier := regexp.MustCompile(`^([A-Za-z]|[A-Za-z_][0-9A-Za-z_]+)$`)
+	return identifier.MatchString(s)
+}
+
+// Convert a parsed type to a text string suitable for bash eval
+// as a right-hand side of an assignment.
+// Handles quoting for string, no quote for number or bool.
+func To_bash(v interface{}) string {
+	var s string
+	switch v.(type) {
+	case bool:
+		s = fmt.Sprintf("%v", v.(bool))
+	case int:
+		s = fmt.Sprintf("%d", v.(int))
+	case float64:
+		s = strconv.FormatFloat(v.(float64), 'g', -1, 64)
+	case string:
+		s = fmt.Sprintf("'%s'", Shellquote(v.(string)))
+	case []string:
+		arr := v.([]string)
+		if len(arr) == 0 {
+			// bash empty array
+			s = "()"
+		} else {
+			// escape all strings
+			arr_out := make([]string, len(arr))
+			for i, e := range arr {
+				arr_out[i] = Shellquote(e)
+			}
+			s = fmt.Sprintf("('%s')", strings.Join(arr_out[:], "' '"))
+		}
+	case nil:
+		s = ""
+	default:
+		panic(fmt.Sprintf("To_bash():unsuported type: %v for '%v'", reflect.TypeOf(v), v))
+	}
+
+	return s
+}
+
+// Output parsed arguments as a single line JSON object, keys are kept verbatim.
+// Values are typed: boolean, number for counters, array for repeatable
+// arguments and null for unset values.
+func (d *Docopts) Print_json(w io.Writer, args docopt.Opts) error {
+	return Format_args(w, &json_format{d: d}, args)
+}
+
+func write_json(w io.Writer, object docopt.Opts) error {
+	enc := json.NewEncoder(w)
+	// keep '<argument>' keys readable, no \u003c escaping
+	enc.SetEscapeHTML(false)
+	return enc.Encode(map[string]interface{}(object))
+}
+
+// Performs output for bash Globals (not bash 4+ assoc) Names are mangled to become
+// suitable for bash eval.
+// If Docopts.Mangle_key is false: simply print left-hand side assignment verbatim.
+// used for --no-mangle
+func (d *Docopts) Print_bash_global(w io.Writer, args docopt.Opts) error {
+	return Format_args(w, new_bash_global_format(d), args)
+}
+
+// Transform a parsed option or place-holder name into a bash identifier if possible.
+// It Docopts.Global_prefix is prepended if given, wrong prefix may produce invalid
+// bash identifier and this method will fail too.
+func (d *Docopts) Name_mangle(elem string) (string, error) {
+	var v string
+
+	if d.Global_prefix == "" && (elem == "-" || elem == "--") {
+		return "", fmt.Errorf("Mangling not supported for: '%s'", elem)
+	}
+
+	if Match(`^<.*>$`, elem) {
+		v = elem[1 : len(elem)-1]
+	} else if Match(`^-[^-]$`, elem) {
+		v = fmt.Sprintf("%c", elem[1])
+	} else if Match(`^--.+$`, elem) {
+		v = elem[2:]
+	} else {
+		// also this case for '-' when d.Global_prefix != ""
+		v = elem
+	}
+
+	// alter output if we have a prefix
+	key_fmt := "%s"
+	if d.Global_prefix != "" {
+		key_fmt = fmt.Sprintf("%s_%%s", d.Global_prefix)
+	}
+
+	v = fmt.Sprintf(key_fmt, strings.Replace(v, "-", "_", -1))
+
+	if !IsBashIdentifier(v) {
+		return "", fmt.Errorf("cannot transform into a bash identifier: '%s' => '%s'", elem, v)
+	}
+
+	return v, nil
+}
+
+// Default Docopts.Arrays template: args_FILE for -A args and <FILE>.
+const Default_arrays = "{assoc}_{name}"
+
+// Expand the Docopts.Arrays template for key: {assoc} is replaced by
+// Docopts.Assoc and {name} by the key mangled without Global_prefix.
+func (d *Docopts) Array_name(key string) (string, error) {
+	no_prefix := *d
+	no_prefix.Global_prefix = ""
+	mangled, err := no_prefix.Name_mangle(key)
+	if err != nil {
+		return "", err
+	}
+	name := strings.NewReplacer("{assoc}", d.Assoc, "{name}", mangled).Replace(d.Arrays)
+	if !IsBashIdentifier(name) {
+		return "", fmt.Errorf("--arrays: not a valid Bash identifier: '%s' => '%s'", key, name)
+	}
+	return name, nil
+}
+
+// helper for lazy typing
+func Match(regex string, source string) bool {
+	matched, _ := regexp.MatchString(regex, source)
+	return matched
+}
+
+// Declaration keyword for bash variables: 'local' inside a function.
+func (d *Docopts) Declare_keyword() string {
+	if d.Local {
+		return "local"
+	}
+	return "declare"
+}
+
+// Change bash exit source code based on '--function' parameter
+func (d *Docopts) Get_exit_code(exit_code int) (str_code string) {
+	if d.Exit_function {
+		str_code = fmt.Sprintf("return %d", exit_code)
+	} else {
+		str_code = fmt.Sprintf("exit %d", exit_code)
+	}
+	return
+}
+
+// Outputs bash source code displaying the error and the usage on stderr, then
+// stopping with status 64.
+func (d *Docopts) Print_bash_error(w io.Writer, err error, usage string) {
+	fmt.Fprintf(w, "echo 'error: %s\n%s' >&2\n%s\n",
+		Shellquote(err.Error()),
+		Shellquote(usage),
+		d.Get_exit_code(64),
+	)
+}
+
+// Outputs bash source code displaying program's help or version and stopping.
+func (d *Docopts) Print_bash_message(w io.Writer, message string) {
+	fmt.Fprintf(w, "echo '%s'\n%s\n", Shellquote(message), d.Get_exit_code(0))
+}
+
+// Same as Print_bash_error but the error is outputed as a JSON object.
+func (d *Docopts) Print_json_error(w io.Writer, err error, usage string) error {
+	msg := make(docopt.Opts)
+	msg["error"] = err.Error()
+	// docopt prepends the error to the usage
+	msg["usage"] = strings.TrimSpace(strings.TrimPrefix(usage, err.Error()))
+	if user_error, ok := err.(*grammar.User_error); ok && len(user_error.Suggestions) > 0 {
+		msg["suggestions"] = user_error.Suggestions
+	}
+	msg["exit_code"] = 64
+	return write_json(w, msg)
+}
+
+// Same as Print_bash_message but the message is outputed as a JSON object.
+func (d *Docopts) Print_json_message(w io.Writer, message string) error {
+	msg := make(docopt.Opts)
+	msg["message"] = message
+	msg["exit_code"] = 0
+	return write_json(w, msg)
+}
@@ -1,8 +1,8 @@
 // vim: set ts=4 sw=4 sts=4 noet:
 //
-// unit test for docopts.go
+// unit test for shellout.go
 //
-package main
+package shellout
 
 import (
 	"bytes"
@@ -12,6 +12,7 @@ import (
 	"testing"
 	// our json loader for common_input_test.json
 	"fmt"
+	"github.com/docopt/docopt-go"
 	"github.com/docopt/docopts/test_json_load"
 )
 
@@ -82,10 +83,7 @@ func TestIsArray(t *testing.T) {
 }
 
 func TestPrint_bash_args(t *testing.T) {
-	// replace out (os.Stdout) by a buffer
-	bak := out
-	out = new(bytes.Buffer)
-	defer func() { out = bak }()
+	out := new(bytes.Buffer)
 
 	//tables := []struct{
 	//    input map[string]interface{}
@@ -130,15 +128,80 @@ func TestPrint_bash_args(t *testing.T) {
 		Output_declare: true,
 	}
 
-	tables, _ := test_json_loader.Load_json("./common_input_test.json")
+	tables, _ := test_json_loader.Load_json("../../common_input_test.json")
 	for _, table := range tables {
-		d.Print_bash_args("args", table.Input)
-		res := out.(*bytes.Buffer).String()
+		d.Print_bash_args(out, "args", table.Input)
+		res := out.String()
 		expect := strings.Join(table.Expect_args[:], "\n") + "\n"
 		if res != expect {
 			t.Errorf("Print_bash_args for '%v'\ngot: '%v'\nwant: '%v'\n", table.Input, res, expect)
 		}
-		out.(*bytes.Buffer).Reset()
+		out.Reset()
+	}
+}
+
+func TestPrint_bash_args_arrays(t *testing.T) {
+	out := new(bytes.Buffer)
+	args := docopt.Opts{"<FILE>": []string{"a", "it's"}, "--path": []string{}, "-v": true}
+	tables := []struct {
+		d      *Docopts
+		expect string
+	}{
+		{&Docopts{Mangle_key: true, Output_declare: true, Arrays: Default_arrays},
+			"declare -A args\nargs['--path,#']=0\ndeclare -a args_path=()\nargs['-v']=true\n" +
+				"args['<FILE>,0']='a'\nargs['<FILE>,1']='it'\\''s'\nargs['<FILE>,#']=2\ndeclare -a args_FILE=('a' 'it'\\''s')\n"},
+		// the prefix isn't part of {name}
+		{&Docopts{Mangle_key: true, Local: true, Global_prefix: "ARGS", Arrays: "{name}_list"},
+			"args['--path,#']=0\npath_list=()\nargs['-v']=true\n" +
+				"args['<FILE>,0']='a'\nargs['<FILE>,1']='it'\\''s'\nargs['<FILE>,#']=2\nFILE_list=('a' 'it'\\''s')\n"},
+	}
+	for _, table := range tables {
+		if err := table.d.Print_bash_args(out, "args", args); err != nil {
+			t.Errorf("Print_bash_args with Arrays '%s' error: %v", table.d.Arrays, err)
+		}
+		if out.String() != table.expect {
+			t.Errorf("Print_bash_args with Arrays '%s'\ngot: '%v'\nwant: '%v'\n", table.d.Arrays, out.String(), table.expect)
+		}
+		out.Reset()
+	}
+
+	// invalid or identical array names
+	invalid := []struct {
+		arrays string
+		args   docopt.Opts
+	}{
+		{"{name}-list", docopt.Opts{"<FILE>": []string{"a"}}},
+		{"files", docopt.Opts{"<FILE>": []string{"a"}, "<dir>": []string{"b"}}},
+	}
+	for _, e := range invalid {
+		d := &Docopts{Mangle_key: true, Arrays: e.arrays}
+		if err := d.Print_bash_args(out, "args", e.args); err == nil {
+			t.Errorf("Print_bash_args with Arrays '%s' expecting error, got: '%v'", e.arrays, out.String())
+		}
+		out.Reset()
+	}
+}
+
+func TestPrint_json(t *testing.T) {
+	out := new(bytes.Buffer)
+
+	d := &Docopts{}
+
+	tables, _ := test_json_loader.Load_json("../../common_input_test.json")
+	for _, table := range tables {
+		if table.Expect_json == "" {
+			continue
+		}
+		err := d.Print_json(out, table.Input)
+		if err != nil {
+			t.Errorf("Print_json doesn't return nil for err: %v\n", err)
+		}
+		res := out.String()
+		expect := table.Expect_json + "\n"
+		if res != expect {
+			t.Errorf("Print_json for '%v'\ngot: '%v'\nwant: '%v'\n", table.Input, res, expect)
+		}
+		out.Reset()
 	}
 }
 
@@ -150,6 +213,7 @@ func TestTo_bash(t *testing.T) {
 		{"pipo", "'pipo'"},
 		{"i''i", "'i'\\'''\\''i'"},
 		{123, "123"},
+		{1.5, "1.5"},
 		{nil, ""},
 		{"", "''"},
 		{[]string{"pipo", "molo"}, "('pipo' 'molo')"},
@@ -183,13 +247,10 @@ func rewrite_prefix(prefix string, expected []string) string {
 }
 
 func TestPrint_bash_global(t *testing.T) {
-	// replace out (os.Stdout) by a buffer
-	bak := out
-	out = new(bytes.Buffer)
-	defer func() { out = bak }()
+	out := new(bytes.Buffer)
 
 	// now loads test from a JSON file
-	tables, _ := test_json_loader.Load_json("./common_input_test.json")
+	tables, _ := test_json_loader.Load_json("../../common_input_test.json")
 
 	// static tables format
 	//tables := []struct{
@@ -229,16 +290,16 @@ func TestPrint_bash_global(t *testing.T) {
 		Mangle_key:    true,
 	}
 	for _, table := range tables {
-		err = d.Print_bash_global(table.Input)
+		err = d.Print_bash_global(out, table.Input)
 		if err != nil {
 			t.Errorf("Print_bash_global doesn't return nil for err: %v\n", err)
 		}
-		res := out.(*bytes.Buffer).String()
+		res := out.String()
 		expect := strings.Join(table.Expect_global[:], "\n") + "\n"
 		if res != expect {
 			t.Errorf("Print_bash_global for '%v'\ngot: '%v'\nwant: '%v'\n", table.Input, res, expect)
 		}
-		out.(*bytes.Buffer).Reset()
+		out.Reset()
 	}
 
 	// without Mangle_key --no-mangle
@@ -247,16 +308,16 @@ func TestPrint_bash_global(t *testing.T) {
 		Mangle_key:    false,
 	}
 	for _, table := range tables {
-		err = d.Print_bash_global(table.Input)
+		err = d.Print_bash_global(out, table.Input)
 		if err != nil {
 			t.Errorf("Print_bash_global doesn't return nil for err: %v\n", err)
 		}
-		res := out.(*bytes.Buffer).String()
+		res := out.String()
 		expect := rewrite_not_mangled(table.Input)
 		if res != expect {
 			t.Errorf("Mangle_key false: Print_bash_global for '%v'\ngot: '%v'\nwant: '%v'\n", table.Input, res, expect)
 		}
-		out.(*bytes.Buffer).Reset()
+		out.Reset()
 	}
 
 	// with Mangle_key and Global_prefix
@@ -265,11 +326,11 @@ func TestPrint_bash_global(t *testing.T) {
 		Mangle_key:    true,
 	}
 	for _, table := range tables {
-		err = d.Print_bash_global(table.Input)
+		err = d.Print_bash_global(out, table.Input)
 		if err != nil {
 			t.Errorf("Print_bash_global doesn't return nil for err: %v\n", err)
 		}
-		res := out.(*bytes.Buffer).String()
+		res := out.String()
 
 		var expect string
 		if len(table.Expect_global_prefix) > 0 {
@@ -281,7 +342,7 @@ func TestPrint_bash_global(t *testing.T) {
 		if res != expect {
 			t.Errorf("with prefix: Print_bash_global for '%v'\ngot: '%v'\nwant: '%v'\n", table.Input, res, expect)
 		}
-		out.(*bytes.Buffer).Reset()
+		out.Reset()
 	}
 
 	// with Mangle_key plus name collision
@@ -289,11 +350,87 @@ func TestPrint_bash_global(t *testing.T) {
 	input_args["--long-option"] = true
 	input_args["<long-option>"] = "dummy_value"
 
-	err = d.Print_bash_global(input_args)
+	err = d.Print_bash_global(out, input_args)
 	if err == nil {
 		t.Errorf("Print_bash_global expecting err on duplicate Mangle_key options")
 	}
-	out.(*bytes.Buffer).Reset()
+	out.Reset()
+}
+
+func TestPrint_bash_local(t *testing.T) {
+	out := new(bytes.Buffer)
+
+	input_args := map[string]interface{}{
+		"--counter": 2,
+		"FILE":      []string{"pipo", "molo"},
+	}
+
+	d := &Docopts{
+		Global_prefix:  "",
+		Mangle_key:     true,
+		Output_declare: true,
+		Local:          true,
+	}
+
+	d.Print_bash_args(out, "args", input_args)
+	expect := "local -A args\nargs['--counter']=2\nargs['FILE,0']='pipo'\nargs['FILE,1']='molo'\nargs['FILE,#']=2\n"
+	res := out.String()
+	if res != expect {
+		t.Errorf("Local: Print_bash_args\ngot: '%v'\nwant: '%v'\n", res, expect)
+	}
+	out.Reset()
+
+	err := d.Print_bash_global(out, input_args)
+	if err != nil {
+		t.Errorf("Print_bash_global doesn't return nil for err: %v\n", err)
+	}
+	expect = "local counter=2\nlocal FILE=('pipo' 'molo')\n"
+	res = out.String()
+	if res != expect {
+		t.Errorf("Local: Print_bash_global\ngot: '%v'\nwant: '%v'\n", res, expect)
+	}
+	out.Reset()
+}
+
+func TestPrint_bash_export_readonly(t *testing.T) {
+	out := new(bytes.Buffer)
+	args := docopt.Opts{"<file>": []string{"a", "it's"}, "--speed": 2, "--name": nil}
+	tables := []struct {
+		d      *Docopts
+		expect string
+	}{
+		{&Docopts{Mangle_key: true, Export: true, Export_separator: ":"},
+			"export name=\nexport speed=2\nexport file='a:it'\\''s'\n"},
+		{&Docopts{Mangle_key: true, Readonly: true},
+			"readonly name=\nreadonly speed=2\nreadonly -a file=('a' 'it'\\''s')\n"},
+		{&Docopts{Mangle_key: true, Export: true, Export_separator: ",", Readonly: true, Global_prefix: "ARGS"},
+			"export ARGS_name=; readonly ARGS_name\nexport ARGS_speed=2; readonly ARGS_speed\n" +
+				"export ARGS_file='a,it'\\''s'; readonly ARGS_file\n"},
+		{&Docopts{Mangle_key: true, Export: true, Export_separator: ":", Readonly: true, Local: true},
+			"local -r -x name=\nlocal -r -x speed=2\nlocal -r -x file='a:it'\\''s'\n"},
+		{&Docopts{Mangle_key: true, Readonly: true, Local: true},
+			"local -r name=\nlocal -r speed=2\nlocal -r file=('a' 'it'\\''s')\n"},
+	}
+	for _, table := range tables {
+		if err := table.d.Print_bash_global(out, args); err != nil {
+			t.Errorf("Print_bash_global for %+v error: %v", table.d, err)
+		}
+		if out.String() != table.expect {
+			t.Errorf("Print_bash_global for %+v\ngot: '%v'\nwant: '%v'\n", table.d, out.String(), table.expect)
+		}
+		out.Reset()
+	}
+}
+
+func TestGet_exit_code(t *testing.T) {
+	d := &Docopts{Exit_function: false}
+	if res := d.Get_exit_code(64); res != "exit 64" {
+		t.Errorf("Get_exit_code got: '%s', want: 'exit 64'", res)
+	}
+	d.Exit_function = true
+	if res := d.Get_exit_code(0); res != "return 0" {
+		t.Errorf("Exit_function: Get_exit_code got: '%s', want: 'return 0'", res)
+	}
 }
 
 type Expected struct {
@@ -367,3 +504,36 @@ func TestName_mangle(t *testing.T) {
 		}
 	}
 }
+
+func TestPrint_result(t *testing.T) {
+	out := new(bytes.Buffer)
+	tables := []struct {
+		d      *Docopts
+		r      *Result
+		expect string
+	}{
+		{&Docopts{Mangle_key: true}, &Result{Args: docopt.Opts{"<name>": "boat", "-v": true}}, "v=true\nname='boat'\n"},
+		{&Docopts{Assoc: "args"}, &Result{Args: docopt.Opts{"-v": true}}, "args['-v']=true\n"},
+		{&Docopts{Json: true}, &Result{Args: docopt.Opts{"-v": true}}, "{\"-v\":true}\n"},
+		{&Docopts{}, &Result{Message: "it's help"}, "echo 'it'\\''s help'\nexit 0\n"},
+		{&Docopts{Exit_function: true}, &Result{Error: errors.New("Unknown command 'x'"), Usage: "Usage: prog"},
+			"echo 'error: Unknown command '\\''x'\\''\nUsage: prog' >&2\nreturn 64\n"},
+		{&Docopts{Json: true}, &Result{Message: "help"}, "{\"exit_code\":0,\"message\":\"help\"}\n"},
+		{&Docopts{Json: true}, &Result{Error: errors.New("bad"), Usage: "bad\nUsage: prog"},
+			"{\"error\":\"bad\",\"exit_code\":64,\"usage\":\"Usage: prog\"}\n"},
+	}
+	for _, table := range tables {
+		if err := table.d.Print_result(out, table.r); err != nil {
+			t.Errorf("Print_result error: %v", err)
+		}
+		if res := out.String(); res != table.expect {
+			t.Errorf("Print_result for %+v\ngot: '%v'\nwant: '%v'\n", table.r, res, table.expect)
+		}
+		out.Reset()
+	}
+
+	d := &Docopts{Assoc: "not valid"}
+	if err := d.Print_result(out, &Result{Args: docopt.Opts{}}); err == nil || out.Len() != 0 {
+		t.Errorf("Print_result expecting error for invalid -A name, got: %v", err)
+	}
+}
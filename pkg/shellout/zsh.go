@@ -0,0 +1,145 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// zsh.go: zsh native output, selected with --shell=zsh.
+//
+// Repeatable arguments are zsh arrays in global mode. Associative arrays
+// can't hold arrays either in zsh, they keep bash's fake nested keys:
+// args[key,#] and args[key,i]. Messages are displayed with print -r, zsh's
+// echo interprets backslashes.
+//
+package shellout
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// zsh special parameters, a mangled name must not overwrite them
+var zsh_reserved = map[string]bool{
+	"argv": true, "ARGC": true, "path": true, "PATH": true, "fpath": true,
+	"cdpath": true, "manpath": true, "mailpath": true, "module_path": true,
+	"psvar": true, "status": true, "pipestatus": true, "signals": true,
+	"options": true, "parameters": true, "commands": true, "functions": true,
+	"aliases": true, "history": true, "reply": true, "REPLY": true,
+	"match": true, "mbegin": true, "mend": true, "prompt": true, "HOME": true,
+}
+
+func zsh_print(w io.Writer, message string, stderr bool) {
+	redirect := ""
+	if stderr {
+		redirect = " >&2"
+	}
+	fmt.Fprintf(w, "print -r -- '%s'%s\n", Shellquote(message), redirect)
+}
+
+// zsh associative array: -A <name>
+type zsh_assoc_format struct {
+	d *Docopts
+}
+
+func (f *zsh_assoc_format) Begin(w io.Writer) error {
+	if !IsBashIdentifier(f.d.Assoc) {
+		return fmt.Errorf("-A: not a valid zsh identifier: '%s'", f.d.Assoc)
+	}
+	if f.d.Output_declare {
+		fmt.Fprintf(w, "%s -A %s\n", f.zsh_declare(), f.d.Assoc)
+	}
+	fmt.Fprintf(w, "%s=(\n", f.d.Assoc)
+	return nil
+}
+
+func (f *zsh_assoc_format) zsh_declare() string {
+	if f.d.Local {
+		return "local"
+	}
+	return "typeset"
+}
+
+// one key value pair of the assignment list, unset values are empty strings
+func (f *zsh_assoc_format) Emit_key(w io.Writer, key string, value interface{}) error {
+	v := To_bash(value)
+	if value == nil {
+		v = "''"
+	}
+	fmt.Fprintf(w, "  '%s' %s\n", Shellquote(key), v)
+	return nil
+}
+
+func (f *zsh_assoc_format) Emit_array(w io.Writer, key string, values []string) error {
+	for index, v := range values {
+		fmt.Fprintf(w, "  '%s,%d' %s\n", Shellquote(key), index, To_bash(v))
+	}
+	fmt.Fprintf(w, "  '%s,#' %d\n", Shellquote(key), len(values))
+	return nil
+}
+
+func (f *zsh_assoc_format) End(w io.Writer) error {
+	fmt.Fprintln(w, ")")
+	return nil
+}
+
+func (f *zsh_assoc_format) Error(w io.Writer, err error, usage string) error {
+	zsh_print(w, fmt.Sprintf("error: %s\n%s", err, usage), true)
+	fmt.Fprintln(w, f.d.Get_exit_code(64))
+	return nil
+}
+
+func (f *zsh_assoc_format) Help(w io.Writer, message string) error {
+	zsh_print(w, message, false)
+	fmt.Fprintln(w, f.d.Get_exit_code(0))
+	return nil
+}
+
+// zsh global variables, repeatable arguments are arrays. Buffered as
+// bash-global.
+type zsh_global_format struct {
+	zsh_assoc_format
+	names *Name_mangler
+	buf   bytes.Buffer
+}
+
+func (f *zsh_global_format) Begin(w io.Writer) error {
+	return nil
+}
+
+func (f *zsh_global_format) Emit_key(w io.Writer, key string, value interface{}) error {
+	name, err := f.names.Name(key)
+	if err != nil || name == "" {
+		return err
+	}
+	if f.d.Local {
+		f.buf.WriteString("local ")
+	}
+	fmt.Fprintf(&f.buf, "%s=%s\n", name, To_bash(value))
+	return nil
+}
+
+func (f *zsh_global_format) Emit_array(w io.Writer, key string, values []string) error {
+	name, err := f.names.Name(key)
+	if err != nil || name == "" {
+		return err
+	}
+	// typeset would make it local inside a function
+	if f.d.Local {
+		f.buf.WriteString("local -a ")
+	}
+	fmt.Fprintf(&f.buf, "%s=%s\n", name, To_bash(values))
+	return nil
+}
+
+func (f *zsh_global_format) End(w io.Writer) error {
+	_, err := f.buf.WriteTo(w)
+	return err
+}
+
+func init() {
+	Register_format("zsh-assoc", func(d *Docopts) OutputFormatter {
+		return &zsh_assoc_format{d}
+	})
+	Register_format("zsh-global", func(d *Docopts) OutputFormatter {
+		names := New_name_mangler(d)
+		names.Reserved = zsh_reserved
+		return &zsh_global_format{zsh_assoc_format: zsh_assoc_format{d}, names: names}
+	})
+}
@@ -0,0 +1,64 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for zsh.go
+//
+package shellout
+
+import (
+	"bytes"
+	"errors"
+	"github.com/docopt/docopt-go"
+	"testing"
+)
+
+func TestZsh_formats(t *testing.T) {
+	args := docopt.Opts{"--speed": 2, "<file>": []string{"a", "it's"}, "--name": nil, "-v": true}
+	tables := []struct {
+		d      *Docopts
+		expect string
+	}{
+		{&Docopts{Shell: "zsh", Mangle_key: true, Output_declare: true, Assoc: "args"},
+			"typeset -A args\nargs=(\n  '--name' ''\n  '--speed' 2\n  '-v' true\n" +
+				"  '<file>,0' 'a'\n  '<file>,1' 'it'\\''s'\n  '<file>,#' 2\n)\n"},
+		{&Docopts{Shell: "zsh", Mangle_key: true, Assoc: "args", Local: true, Output_declare: true},
+			"local -A args\nargs=(\n  '--name' ''\n  '--speed' 2\n  '-v' true\n" +
+				"  '<file>,0' 'a'\n  '<file>,1' 'it'\\''s'\n  '<file>,#' 2\n)\n"},
+		{&Docopts{Shell: "zsh", Mangle_key: true},
+			"name=\nspeed=2\nv=true\nfile=('a' 'it'\\''s')\n"},
+		{&Docopts{Shell: "zsh", Mangle_key: true, Global_prefix: "ARGS", Local: true},
+			"local ARGS_name=\nlocal ARGS_speed=2\nlocal ARGS_v=true\nlocal -a ARGS_file=('a' 'it'\\''s')\n"},
+	}
+	var out bytes.Buffer
+	for _, table := range tables {
+		if err := table.d.Print_result(&out, &Result{Args: args}); err != nil {
+			t.Errorf("zsh format %s error: %v", table.d.Format_name(), err)
+		}
+		if out.String() != table.expect {
+			t.Errorf("zsh format %s\ngot: '%s'\nwant: '%s'", table.d.Format_name(), out.String(), table.expect)
+		}
+		out.Reset()
+	}
+
+	// special parameters aren't overwritten
+	d := &Docopts{Shell: "zsh", Mangle_key: true}
+	if err := d.Print_result(&out, &Result{Args: docopt.Opts{"<path>": "x"}}); err == nil || out.Len() != 0 {
+		t.Errorf("zsh-global expecting error for <path>, got: %v '%s'", err, out.String())
+	}
+	d.Global_prefix = "ARGS"
+	if err := d.Print_result(&out, &Result{Args: docopt.Opts{"<path>": "x"}}); err != nil || out.String() != "ARGS_path='x'\n" {
+		t.Errorf("zsh-global with prefix got: %v '%s'", err, out.String())
+	}
+	out.Reset()
+
+	// messages with print -r, zsh's echo interprets backslashes
+	d = &Docopts{Shell: "zsh", Mangle_key: true, Exit_function: true}
+	d.Print_result(&out, &Result{Error: errors.New(`bad \n`), Usage: "Usage: prog"})
+	if expect := "print -r -- 'error: bad \\n\nUsage: prog' >&2\nreturn 64\n"; out.String() != expect {
+		t.Errorf("zsh error got: '%s'\nwant: '%s'", out.String(), expect)
+	}
+	out.Reset()
+	d.Print_result(&out, &Result{Message: "it's help"})
+	if expect := "print -r -- 'it'\\''s help'\nreturn 0\n"; out.String() != expect {
+		t.Errorf("zsh help got: '%s'\nwant: '%s'", out.String(), expect)
+	}
+}
@@ -0,0 +1,117 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// run.go: docopts run, a script interpreter: #!/usr/bin/env docopts-run
+//
+// The script's usage is extracted from its comment header, its arguments are
+// parsed and bash runs the script with the variables already defined.
+//
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/docopt/docopts/pkg/shellout"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var Run_usage = `Run SCRIPT with bash, its arguments already parsed: the variables are
+defined before the script starts, no helper library or eval line is needed.
+
+The help message and the version are found in the script's comments, see:
+docopts extract --help. On --help, --version or a usage error the message is
+displayed and the script isn't run.
+
+Used as a script interpreter, docopts installed or linked as docopts-run:
+  #!/usr/bin/env docopts-run
+or, with options:
+  #!/usr/bin/env -S docopts run -A args
+
+Usage:
+  docopts run [options] SCRIPT [<argv>...]
+
+Arguments:
+  SCRIPT                        The bash script to run.
+
+Options:
+  -h, --help                    Show this help.
+  -A <name>                     Store the arguments in the Bash 4+ associative
+                                array <name>.
+  -G <prefix>                   Prefix of the global variables names:
+                                <prefix>_{mangled_args}={value}
+  -O, --options-first           Disallow interspersing options and positional
+                                arguments in <argv>.
+  -H, --no-help                 Don't handle --help and --version specially.
+  --interpreter=<path>          The shell running the script.
+                                [default: bash]
+`
+
+// The name of docopts used as a script interpreter.
+const Run_name = "docopts-run"
+
+// Bash code defining the variables for argv, or displaying the message and
+// exiting, then sourcing the script: $0 and the positional parameters are
+// the script's.
+func run_code(d *shellout.Docopts, p *shellout.Parser, filename, script string, argv []string) (string, error) {
+	doc, err := Extract_usage(script)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", filename, err)
+	}
+	p.Version = Extract_version(script)
+	result, err := p.Parse(doc, argv)
+	if err != nil {
+		return "", fmt.Errorf("%s: USAGE: %v", filename, err)
+	}
+	var code bytes.Buffer
+	if err := d.Print_result(&code, result); err != nil {
+		return "", err
+	}
+	// '.' would search a name without slash in PATH
+	if !strings.Contains(filename, "/") {
+		filename = "./" + filename
+	}
+	fmt.Fprintf(&code, ". '%s'\n", shellout.Shellquote(filename))
+	return code.String(), nil
+}
+
+func docopts_run(v *Verb, argv []string) {
+	arguments := v.Parse(argv)
+	filename := arguments["SCRIPT"].(string)
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		docopts_error("run: %v", err)
+	}
+
+	p := &shellout.Parser{
+		Options_first: arguments["--options-first"].(bool),
+		No_help:       arguments["--no-help"].(bool),
+	}
+	d := &shellout.Docopts{Mangle_key: true, Output_declare: true}
+	d.Assoc, _ = arguments.String("-A")
+	d.Global_prefix, _ = arguments.String("-G")
+	code, err := run_code(d, p, filename, string(bytes), arguments["<argv>"].([]string))
+	if err != nil {
+		docopts_error("run: %v", err)
+	}
+
+	interpreter, _ := arguments.String("--interpreter")
+	command := append([]string{interpreter, "-c", code, filename}, arguments["<argv>"].([]string)...)
+	err = exec_command(command, os.Environ())
+	docopts_error("run: %v", err)
+}
+
+// Is docopts invoked as docopts-run, the script interpreter.
+func Is_run_name(arg0 string) bool {
+	return strings.TrimSuffix(filepath.Base(arg0), ".exe") == Run_name
+}
+
+func init() {
+	Register_verb(&Verb{
+		Name:    "run",
+		Summary: "Run a bash script with its arguments already parsed.",
+		Usage:   Run_usage,
+		Run:     docopts_run,
+	})
+}
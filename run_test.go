@@ -0,0 +1,55 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for run.go
+//
+package main
+
+import (
+	"github.com/docopt/docopts/pkg/shellout"
+	"strings"
+	"testing"
+)
+
+func TestRun_code(t *testing.T) {
+	script := "# Usage: prog <file>...\n# ----\n# prog 1.0\n\necho\n"
+	tables := []struct {
+		d        *shellout.Docopts
+		filename string
+		argv     []string
+		expect   string
+	}{
+		{&shellout.Docopts{Mangle_key: true}, "prog", []string{"a", "b"},
+			"file=('a' 'b')\n. './prog'\n"},
+		{&shellout.Docopts{Mangle_key: true, Assoc: "args"}, "/bin/it's", []string{"a"},
+			"args['<file>,0']='a'\nargs['<file>,#']=1\n. '/bin/it'\\''s'\n"},
+		{&shellout.Docopts{Mangle_key: true}, "prog", []string{"--version"},
+			"echo 'prog 1.0'\nexit 0\n. './prog'\n"},
+	}
+	for _, table := range tables {
+		code, err := run_code(table.d, &shellout.Parser{}, table.filename, script, table.argv)
+		if err != nil || code != table.expect {
+			t.Errorf("run_code %v got: %v '%s'\nwant: '%s'", table.argv, err, code, table.expect)
+		}
+	}
+
+	code, _ := run_code(&shellout.Docopts{Mangle_key: true}, &shellout.Parser{}, "prog", script, []string{})
+	if !strings.HasPrefix(code, "echo 'error: ") || !strings.Contains(code, "exit 64\n") {
+		t.Errorf("run_code usage error got: '%s'", code)
+	}
+	if _, err := run_code(&shellout.Docopts{}, &shellout.Parser{}, "prog", "echo no usage\n", []string{}); err == nil {
+		t.Errorf("run_code expecting error without usage")
+	}
+}
+
+func TestIs_run_name(t *testing.T) {
+	for name, expect := range map[string]bool{
+		"/usr/local/bin/docopts-run": true,
+		"docopts-run.exe":            true,
+		"docopts":                    false,
+		"/usr/bin/docopts-runner":    false,
+	} {
+		if Is_run_name(name) != expect {
+			t.Errorf("Is_run_name(%s) want: %v", name, expect)
+		}
+	}
+}
@@ -11,6 +11,7 @@ type TestString struct {
 	Expect_args          []string
 	Expect_global        []string
 	Expect_global_prefix []string
+	Expect_json          string
 }
 
 func (t TestString) ToString() string {
@@ -36,6 +37,7 @@ func (t TestString) toString() (str string) {
 
 	str += fmt.Sprintf("Expect_global : %v\n", t.Expect_global)
 	str += fmt.Sprintf("Expect_global_prefix : %v\n", t.Expect_global_prefix)
+	str += fmt.Sprintf("Expect_json : %v\n", t.Expect_json)
 
 	return str
 }
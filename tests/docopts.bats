@@ -5,6 +5,9 @@
 # run with bats
 #
 
+# the docopts of this tree, docopts.sh calls it
+PATH=..:$PATH
+
 source ../docopts.sh
 
 @test "docopt_get_help_string" {
@@ -253,3 +256,35 @@ EOF
     [[ ${lines[1]} == 'ARGS_TEXT=some_text' ]]
     rm $tmp
 }
+
+@test "docopt_get_help_string and docopt_get_version_string without docopts extract" {
+    # an older docopts, without the extract verb
+    docopts() {
+        echo "Usage: docopts [options] -h <msg> : [<argv>...]"
+    }
+    tmp=./tmp_docopt_get_help_string_fallback
+    cat <<EOF > $tmp
+#!/usr/bin/env bash
+#
+# Usage: rock [options] <argv>...
+#
+# Options:
+#       --verbose  Generate verbose messages.
+# ----
+# rock 0.1.0
+# License RIT (Robot Institute of Technology)
+
+# an empty line above
+EOF
+    run docopt_get_help_string $tmp
+    echo "$output"
+    [[ $status -eq 0 ]]
+    [[ "${lines[0]}" == "Usage: rock [options] <argv>..." ]]
+
+    run docopt_get_version_string $tmp
+    echo "$output"
+    [[ "${lines[0]}" == "rock 0.1.0" ]]
+    [[ ${#lines[@]} -eq 2 ]]
+
+    rm -f $tmp
+}
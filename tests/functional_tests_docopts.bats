@@ -82,3 +82,549 @@ Usage: prog dump [-]
     [[ "$output" =~ $expected_regexp ]]
     [[ ${#lines[@]} -eq 1 ]]
 }
+
+@test "--json outputs typed values" {
+    usage='Usage: prog [-v...] [--speed=<kn>] <file>...'
+    run $DOCOPTS_BIN --json -h "$usage" : -vv a.txt "it's"
+    echo "$output"
+    [[ $status -eq 0 ]]
+    [[ $output == '{"--speed":null,"-v":2,"<file>":["a.txt","it'"'"'s"]}' ]]
+}
+
+@test "--json reports errors and help as JSON" {
+    usage='Usage: prog [--help] <file>'
+    run $DOCOPTS_BIN --json -h "$usage" :
+    echo "$output"
+    [[ $status -eq 1 ]]
+    regexp='^\{"error":.*"exit_code":64,"usage":"Usage: prog \[--help\] <file>"\}$'
+    [[ "$output" =~ $regexp ]]
+
+    run $DOCOPTS_BIN --json -h "$usage" : --help
+    echo "$output"
+    [[ $status -eq 0 ]]
+    [[ $output == '{"exit_code":0,"message":"Usage: prog [--help] <file>"}' ]]
+}
+
+@test "parse verb outputs the same as the legacy command line" {
+    usage='Usage: prog [-v] <file>...'
+    run $DOCOPTS_BIN -A args -h "$usage" : -v a.txt
+    [[ $status -eq 0 ]]
+    legacy="$output"
+
+    run $DOCOPTS_BIN parse -A args "$usage" : -v a.txt
+    echo "$output"
+    [[ $status -eq 0 ]]
+    [[ "$output" == "$legacy" ]]
+
+    run $DOCOPTS_BIN compat -A args -h "$usage" : -v a.txt
+    [[ $status -eq 0 ]]
+    [[ "$output" == "$legacy" ]]
+}
+
+@test "parse verb -h is only docopts help" {
+    run $DOCOPTS_BIN parse -h
+    echo "$output"
+    [[ $status -eq 0 ]]
+    regexp='docopts parse \[options\] USAGE'
+    [[ "$output" =~ $regexp ]]
+
+    # --help after USAGE belongs to the parsed program
+    run $DOCOPTS_BIN parse 'Usage: prog [--help]' : --help
+    echo "$output"
+    [[ $status -eq 0 ]]
+    [[ "${lines[1]}" == "exit 0" ]]
+}
+
+@test "completion bash completes commands and options" {
+    usage="
+Usage:
+  prog ship new <name>...
+  prog ship shoot <x> <y> [--speed=<kn>]
+  prog mine (set|remove) <x> <y>
+
+Options:
+  --speed=<kn>  Speed in knots [default: 10].
+"
+    run $DOCOPTS_BIN completion bash "$usage"
+    echo "$output"
+    [[ $status -eq 0 ]]
+    eval "$output"
+
+    COMP_WORDS=(prog ship '')
+    COMP_CWORD=2
+    _prog_docopts_complete
+    [[ "${COMPREPLY[*]}" == "new shoot" ]]
+
+    # commands starting several patterns are given once
+    COMP_WORDS=(prog '')
+    COMP_CWORD=1
+    _prog_docopts_complete
+    [[ "${COMPREPLY[*]}" == "mine ship" ]]
+
+    COMP_WORDS=(prog ship shoot 1 2 --sp)
+    COMP_CWORD=5
+    _prog_docopts_complete
+    [[ "${COMPREPLY[*]}" == "--speed" ]]
+}
+
+@test "--function parses function arguments with return and local" {
+    usage='Usage: myfunc [-v] <name>'
+    myfunc() {
+        eval "$($DOCOPTS_BIN --function -A args -h "$usage" : "$@")"
+        echo "name=${args[<name>]}"
+    }
+    myfunc_global() {
+        eval "$($DOCOPTS_BIN parse --function "$usage" : "$@")"
+        echo "name=$name"
+    }
+
+    run myfunc -v pipo
+    echo "$output"
+    [[ $status -eq 0 ]]
+    [[ $output == "name=pipo" ]]
+
+    name=outer
+    myfunc_global molo
+    # local variable doesn't leak
+    [[ $name == outer ]]
+
+    # usage error returns from the function only
+    run bash -c "$(declare -f myfunc); DOCOPTS_BIN=$DOCOPTS_BIN usage='$usage'; myfunc; echo \"returned \$?\""
+    echo "$output"
+    [[ $status -eq 0 ]]
+    [[ ${lines[-1]} == "returned 64" ]]
+}
+
+@test "parse errors are named" {
+    usage='Usage:
+  mytool generate <name>
+  mytool -d | --debug
+  mytool --speed=<kn>'
+
+    run $DOCOPTS_BIN -h "$usage" : --junkoption
+    echo "$output"
+    [[ $status -eq 1 ]]
+    [[ ${lines[0]} == "echo 'error: Invalid option '\\''--junkoption'\\''" ]]
+    [[ ${lines[1]} == "Usage:" ]]
+
+    run $DOCOPTS_BIN -h "$usage" : generate foo -d
+    echo "$output"
+    [[ ${lines[0]} == "echo 'error: Option -d is not allowed with command '\\''generate'\\''" ]]
+
+    run $DOCOPTS_BIN -h "$usage" : generate
+    [[ ${lines[0]} == "echo 'error: Missing required argument <name>" ]]
+
+    run $DOCOPTS_BIN -h "$usage" : --speed
+    [[ ${lines[0]} == "echo 'error: --speed requires a value" ]]
+}
+
+@test "parse errors suggest mistyped commands and options" {
+    usage='Usage:
+  naval_fate ship new <name>...
+  naval_fate ship <name> move <x> <y> [--speed=<kn>]
+  naval_fate mine (set|remove) <x> <y>'
+
+    run $DOCOPTS_BIN -h "$usage" : shipp new boat
+    echo "$output"
+    [[ ${lines[0]} == "echo 'error: Unknown command '\\''shipp'\\'', did you mean '\\''ship'\\''?" ]]
+
+    run $DOCOPTS_BIN -h "$usage" : ship boat move 1 2 --sped=5
+    echo "$output"
+    [[ ${lines[0]} == "echo 'error: Invalid option '\\''--sped'\\'', did you mean '\\''--speed'\\''?" ]]
+
+    run $DOCOPTS_BIN --json -h "$usage" : shipp new boat
+    echo "$output"
+    [[ $output == *'"suggestions":["ship"]'* ]]
+}
+
+@test "generate a standalone parser giving the same variables as docopts" {
+    usage='Usage: prog [-v...] [--speed=<kn>] ship <name>...
+
+Options:
+  --speed=<kn>  Speed [default: 10].'
+
+    script=$BATS_TMPDIR/generated.sh
+    $DOCOPTS_BIN generate -A args -n parse_args "$usage" > $script
+
+    for argv in "ship a b" "-vv --speed=3 ship 'it'\''s'" "--sp 5 ship x -v"; do
+        expect=$(eval "set -- $argv"; eval "$($DOCOPTS_BIN -A args -h "$usage" : "$@")"; declare -p args)
+        run bash -c "source $script; set -- $argv; parse_args \"\$@\"; declare -p args"
+        echo "$output"
+        [[ $status -eq 0 ]]
+        [[ $output == "$expect" ]]
+    done
+
+    run bash -c "source $script; parse_args ship"
+    echo "$output"
+    [[ $status -eq 64 ]]
+    [[ ${lines[0]} == "error: Invalid arguments" ]]
+
+    # regenerated in place between markers
+    printf '#!/bin/bash\n# Usage: prog <x>\n\n%s\n%s\nparse_args "$@"\necho "x=$x"\n' \
+        '# >>> docopts generate >>>' '# <<< docopts generate <<<' > $script
+    $DOCOPTS_BIN generate -n parse_args -f $script
+    $DOCOPTS_BIN generate -n parse_args -f $script
+    run bash $script pipo
+    echo "$output"
+    [[ $output == "x=pipo" ]]
+    [[ $(grep -c '^parse_args()' $script) -eq 1 ]]
+
+    # the version is read from the script too
+    printf '#!/bin/bash\n# Usage: prog [--version] <x>\n#\n# ----\n# prog 1.0\n\n%s\n%s\nparse_args "$@"\n' \
+        '# >>> docopts generate >>>' '# <<< docopts generate <<<' > $script
+    $DOCOPTS_BIN generate -n parse_args -f $script
+    run bash $script --version
+    echo "$output"
+    [[ $status -eq 0 ]]
+    [[ $output == "prog 1.0" ]]
+}
+
+@test "debug explains matching on stderr, stdout is still evaluable" {
+    usage='Usage: prog [-v] ship <name>'
+
+    out=$($DOCOPTS_BIN debug "$usage" : ship boat 2> $BATS_TMPDIR/debug.err)
+    eval "$out"
+    [[ $name == boat ]]
+    grep -q '^################## pattern tree ##################$' $BATS_TMPDIR/debug.err
+    grep -q "Argument <name>: matched 'boat'" $BATS_TMPDIR/debug.err
+
+    run $DOCOPTS_BIN debug --explain-parsed "$usage" : ship boat
+    [[ $output != *"argument match"* ]]
+    [[ $output == *"pattern tree"* ]]
+
+    # --debug output doesn't break eval any more
+    out=$($DOCOPTS_BIN --debug -h "$usage" : ship boat 2> /dev/null)
+    eval "$out"
+    [[ $name == boat ]]
+}
+
+@test "typed options are checked and converted" {
+    usage='Usage: prog [--speed=<kn>] [--mode=<m>]
+
+Options:
+  --speed=<kn>  Speed [type: int] [default: 10].
+  --mode=<m>    Mode [choices: fast slow].'
+
+    run $DOCOPTS_BIN -h "$usage" :
+    echo "$output"
+    [[ $status -eq 0 ]]
+    [[ " ${lines[*]} " == *" speed=10 "* ]]
+
+    run $DOCOPTS_BIN -h "$usage" : --speed=fast
+    echo "$output"
+    [[ $output == *"error: --speed must be an int, got "*fast* ]]
+    [[ $output == *"exit 64" ]]
+
+    run $DOCOPTS_BIN -h "$usage" : --mode=slwo
+    [[ $output == *"did you mean "*slow* ]]
+}
+
+@test "options fall back to environment variables" {
+    usage='Usage: prog [--token=<t>]
+
+Options:
+  --token=<t>  API token [env: MYTOOL_TOKEN] [default: none].'
+
+    run $DOCOPTS_BIN -h "$usage" :
+    [[ $output == "token='none'" ]]
+
+    MYTOOL_TOKEN=secret run $DOCOPTS_BIN -h "$usage" :
+    [[ $output == "token='secret'" ]]
+
+    MYTOOL_TOKEN=secret run $DOCOPTS_BIN -h "$usage" : --token=given
+    [[ $output == "token='given'" ]]
+}
+
+@test "options are merged from a config file" {
+    usage='Usage: prog [--speed=<kn>] [--config=<f>]
+
+Options:
+  --speed=<kn>  Speed [env: PROG_SPEED] [default: 10].
+  --config=<f>  Config file.'
+    config=$BATS_TMPDIR/prog.json
+    echo '{"speed": 12}' > $config
+
+    run $DOCOPTS_BIN --config-option=--config -h "$usage" : --config $config
+    echo "$output"
+    [[ ${lines[1]} == "speed='12'" ]]
+
+    PROG_SPEED=20 run $DOCOPTS_BIN parse --config-option=--config "$usage" : --config $config
+    [[ ${lines[1]} == "speed='20'" ]]
+
+    echo '{"sped": 12}' > $config
+    run $DOCOPTS_BIN --config-option=--config -h "$usage" : --config $config
+    echo "$output"
+    [[ $output == *"unknown key "*sped* ]]
+    [[ $output == *"exit 64" ]]
+}
+
+@test "--format selects the output format" {
+    run $DOCOPTS_BIN --format=no-mangle -h 'Usage: prog <x>' : a
+    [[ $output == "<x>='a'" ]]
+
+    run $DOCOPTS_BIN parse --format=json -A args 'Usage: prog <x>' : a
+    [[ $output == '{"<x>":"a"}' ]]
+
+    run $DOCOPTS_BIN --format=bash-assoc -h 'Usage: prog <x>' : a
+    [[ $status -ne 0 ]]
+    [[ $output == *"requires -A"* ]]
+
+    run $DOCOPTS_BIN --format=pipo -h 'Usage: prog <x>' : a
+    [[ $status -ne 0 ]]
+    [[ $output == *"known formats: "* ]]
+}
+
+@test "--shell=zsh outputs zsh code" {
+    run $DOCOPTS_BIN --shell=zsh -h 'Usage: prog <file>...' : a b
+    [[ $output == "file=('a' 'b')" ]]
+
+    run $DOCOPTS_BIN parse --shell=zsh -A args 'Usage: prog <file>' : a
+    [[ ${lines[0]} == "typeset -A args" ]]
+    [[ ${lines[2]} == "  '<file>' 'a'" ]]
+
+    run $DOCOPTS_BIN --shell=zsh -h 'Usage: prog <file>' :
+    [[ ${lines[0]} == "print -r -- 'error: "* ]]
+    [[ $output == *"exit 64" ]]
+
+    run $DOCOPTS_BIN --shell=pipo -h 'Usage: prog <file>' : a
+    [[ $status -ne 0 ]]
+}
+
+@test "--shell=fish outputs fish code" {
+    run $DOCOPTS_BIN --shell=fish -h 'Usage: prog [--name=<n>] <file>...' : a "it's"
+    [[ $status -eq 0 ]]
+    [[ ${lines[0]} == "set -g name" ]]
+    [[ ${lines[1]} == "set -g file 'a' 'it\\'s'" ]]
+    run $DOCOPTS_BIN --shell=fish -A args -h 'Usage: prog <file>' : a
+    [[ $status -eq 1 ]]
+    [[ $output == *"fish has no associative arrays"* ]]
+    run $DOCOPTS_BIN --shell=fish --function -h 'Usage: prog <file>' :
+    [[ $status -eq 1 ]]
+    [[ ${lines[0]} == "echo 'error: "* ]]
+    [[ $output == *"' >&2; return 64" ]]
+}
+
+@test "--shell=sh output is evaluated by dash" {
+    usage='Usage: prog [--name=<n>] <file>...'
+    run $DOCOPTS_BIN --shell=sh -h "$usage" : a "it's \\n"
+    [[ $status -eq 0 ]]
+    [[ ${lines[1]} == "file_COUNT=2" ]]
+    run sh -c 'eval "$($0 --shell=sh -h "$1" : a "it'\''s \\n")"; printf "%s|" "$file_COUNT" "$file_0" "$file_1"' $DOCOPTS_BIN "$usage"
+    [[ $output == "2|a|it's \\n|" ]]
+    run sh -c 'eval "$($0 --shell=sh -h "$1" :)"; echo not reached' $DOCOPTS_BIN "$usage"
+    [[ $status -eq 64 ]]
+    [[ $output == "error: "* ]]
+}
+
+@test "--arrays outputs bash indexed arrays with -A" {
+    run $DOCOPTS_BIN --arrays -A args -h 'Usage: prog <FILE>...' : a 'b c'
+    [[ $status -eq 0 ]]
+    [[ ${lines[-1]} == "declare -a args_FILE=('a' 'b c')" ]]
+    eval "$output"
+    [[ ${#args_FILE[@]} -eq 2 ]]
+    [[ ${args_FILE[1]} == 'b c' ]]
+
+    run $DOCOPTS_BIN --arrays -h 'Usage: prog <FILE>...' : a
+    [[ $status -ne 0 ]]
+}
+
+@test "generated parser checks annotations and reads the environment as docopts" {
+    usage='Usage: prog [--speed=<kn>] [--mode=<m>] [--name=<n>] [-i <f>]... [--token=<t>] [-n <n>]... <x>
+
+Options:
+  --speed=<kn>  Speed [type: int] [env: T_SPEED] [default: 010].
+  --mode=<m>    Mode [choices: fast slow].
+  --name=<n>    Name [pattern: ^[a-z]+$].
+  -i <f>        Inputs [type: path, exists].
+  --token=<t>   Token [env: T_TOKEN].
+  -n <n>        Numbers [type: int] [env: T_NUMS].'
+
+    script=$BATS_TMPDIR/generated.sh
+    $DOCOPTS_BIN generate -A args -n parse_args "$usage" > $script
+
+    for environ in ":" "export T_SPEED=-3 T_TOKEN= T_NUMS='4  5'" "export T_TOKEN=tok T_NUMS="; do
+        for argv in "a" "--speed=+07 a" "--mode fast --name abc -i / -n 1 -n 02 a" \
+                "--mode slwo a" "--name A1 a" "-i /nope a" "--speed x a" "-n x a"; do
+            expect=$(bash -c "$environ; set -- $argv; eval \"\$($DOCOPTS_BIN -A args -h '$usage' : \"\$@\")\"; declare -p args" 2>&1) \
+                && expect_status=0 || expect_status=$?
+            run bash -c "$environ; source $script; set -- $argv; parse_args \"\$@\"; declare -p args"
+            echo "$environ $argv: $output"
+            [[ $status -eq $expect_status ]]
+            if (( status == 0 )) ; then
+                [[ $output == "$expect" ]]
+            else
+                # the same error, without suggestion
+                [[ ${expect%%$'\n'*} == "${lines[0]}"* ]]
+            fi
+        done
+    done
+
+    run $DOCOPTS_BIN generate 'Usage: prog [--ratio=<r>]
+
+Options:
+  --ratio=<r>  Ratio [type: float].'
+    [[ $status -ne 0 ]]
+    [[ $output == *"[type: float] isn't supported"* ]]
+}
+
+@test "--nameref fills the caller's associative array" {
+    parse_into() {
+        local -n ref=$1
+        shift
+        eval "$($DOCOPTS_BIN --nameref=ref -h 'Usage: prog [--speed=<kn>] <file>...' : "$@")"
+    }
+    outer() {
+        local -A opts
+        parse_into opts --speed 3 a b
+        [[ ${opts[--speed]} == 3 ]] && [[ ${opts[<file>,#]} == 2 ]]
+    }
+    outer
+    [[ -z ${opts+x} ]]
+    parse_into global_opts a
+    [[ ${global_opts[<file>,0]} == a ]]
+
+    run $DOCOPTS_BIN --nameref=ref -h 'Usage: prog <file>' :
+    [[ $output == *"return 64" ]]
+}
+
+@test "docopt_parse_into stops on --help and returns usage errors" {
+    bin_dir=$(cd $(dirname $DOCOPTS_BIN) && pwd)
+    main='source ../docopts.sh
+HELP="Usage: prog [--speed=<kn>] <file>"
+main() {
+    local -A help
+    docopt_parse_into help "$@" || exit $?
+    echo "parsed ${help[<file>]} ${help[--speed]}"
+}
+main "$@"'
+    run env PATH=$bin_dir:$PATH bash -c "$main" prog --speed 3 a
+    [[ $status -eq 0 ]]
+    [[ $output == "parsed a 3" ]]
+    run env PATH=$bin_dir:$PATH bash -c "$main" prog --help
+    [[ $status -eq 0 ]]
+    [[ $output == "Usage: prog [--speed=<kn>] <file>" ]]
+    run env PATH=$bin_dir:$PATH bash -c "$main" prog
+    [[ $status -eq 64 ]]
+    [[ $output != *parsed* ]]
+}
+
+@test "--export and --readonly global variables" {
+    eval "$($DOCOPTS_BIN --export -G OPT -h 'Usage: prog <file>...' : a b)"
+    run bash -c 'echo "$OPT_file"'
+    [[ $output == "a:b" ]]
+
+    run bash -c 'eval "$($0 --readonly -h "Usage: prog <name>" : x)"; name=y' $DOCOPTS_BIN
+    [[ $status -ne 0 ]]
+    [[ $output == *"readonly variable"* ]]
+
+    run $DOCOPTS_BIN --export -A args -h 'Usage: prog <name>' : x
+    [[ $status -ne 0 ]]
+}
+
+@test "docopts exec runs the command with the parsed environment" {
+    usage='Usage: prog [--speed=<kn>] <file>...'
+    run $DOCOPTS_BIN exec -h "$usage" --prefix=OPT -- sh -c 'echo "$OPT_speed|$OPT_file|$1"' sh x : --speed 3 a 'b c'
+    [[ $status -eq 0 ]]
+    [[ $output == "3|a:b c|x" ]]
+
+    run $DOCOPTS_BIN exec "$usage" -- echo not reached :
+    [[ $status -eq 64 ]]
+    [[ $output == "error: "* ]]
+    [[ $output != *"not reached"* ]]
+
+    run $DOCOPTS_BIN exec "$usage" -- echo missing colon
+    [[ $status -eq 1 ]]
+}
+
+@test "docopts run is a script interpreter" {
+    tmp=$(mktemp -d)
+    cat > $tmp/script <<'SCRIPT'
+# Usage: script [--name=<n>] <file>...
+#
+# Options:
+#   --name=<n>  Who [default: world].
+# ----
+# script 1.0
+
+echo "$name|${file[1]}|$#"
+SCRIPT
+    run $DOCOPTS_BIN run $tmp/script a 'b c'
+    [[ $output == "world|b c|2" ]]
+    run $DOCOPTS_BIN run $tmp/script --version
+    [[ $output == "script 1.0" ]]
+    run $DOCOPTS_BIN run $tmp/script
+    [[ $status -eq 64 ]]
+
+    ln -s $(cd $(dirname $DOCOPTS_BIN) && pwd)/$(basename $DOCOPTS_BIN) $tmp/docopts-run
+    sed -i '1i #!/usr/bin/env docopts-run' $tmp/script
+    chmod +x $tmp/script
+    run env PATH=$tmp:$PATH $tmp/script --name=me x
+    rm -rf $tmp
+    [[ $output == "me||2" ]]
+}
+
+@test "docopts extract the usage and version of a script" {
+    script='#!/bin/bash
+    # Usage: prog [-v]
+    #
+    # Options:
+    #   -v  Verbose.
+    # ----
+    # prog 1.0
+'
+    run $DOCOPTS_BIN extract --usage - <<< "$script"
+    [[ ${lines[0]} == "Usage: prog [-v]" ]]
+    [[ ${lines[-1]} == "  -v  Verbose." ]]
+    run $DOCOPTS_BIN extract --version - <<< "$script"
+    [[ $output == "prog 1.0" ]]
+    run $DOCOPTS_BIN extract --usage - <<< "echo no usage"
+    [[ $status -eq 1 ]]
+    [[ $output == *"no Usage: block"* ]]
+}
+
+@test "docopts man generates a man page" {
+    usage='Naval Fate.
+
+Usage: naval_fate ship <name> [--speed=<kn>]
+
+Options:
+  --speed=<kn>  Speed in knots [default: 10].'
+    run $DOCOPTS_BIN man -V 'naval_fate 2.0' "$usage"
+    [[ $status -eq 0 ]]
+    [[ ${lines[0]} == '.TH "NAVAL_FATE" "1" "" "naval_fate 2.0"' ]]
+    [[ $output == *'naval_fate \- Naval Fate.'* ]]
+    [[ $output == *'Default: \fI10\fR'* ]]
+    [[ ${lines[-2]} == 'naval_fate 2.0' ]]
+}
+
+@test "docopts doc generates Markdown documentation" {
+    usage='Naval Fate.
+
+Usage: naval_fate ship <name> [--speed=<kn>]
+
+Options:
+  --speed=<kn>  Speed in knots [default: 10].'
+    run $DOCOPTS_BIN doc --level=2 "$usage"
+    [[ $status -eq 0 ]]
+    [[ ${lines[0]} == '## naval_fate' ]]
+    [[ $output == *'|  | `--speed` | `<kn>` | `10` | Speed in knots. |'* ]]
+    run $DOCOPTS_BIN doc --html "$usage"
+    [[ $status -eq 0 ]]
+    [[ ${lines[0]} == '<h1>naval_fate</h1>' ]]
+}
+
+@test "docopts lint reports usage problems" {
+    run $DOCOPTS_BIN lint 'Usage: prog [options] <file>
+
+Options:
+  -v  Verbose.'
+    [[ $status -eq 0 ]]
+    [[ -z $output ]]
+    run $DOCOPTS_BIN lint 'Usage: prog [--dry-run] <dry-run> -'
+    [[ $status -eq 1 ]]
+    [[ ${lines[1]} == "USAGE: collision: --dry-run and <dry-run> are both mangled to 'dry_run'" ]]
+    [[ ${lines[2]} == "USAGE: unmangleable: Mangling not supported for: '-'" ]]
+    run $DOCOPTS_BIN lint --json --ignore=undescribed-option,unmangleable 'Usage: prog [--dry-run] <dry-run>'
+    [[ $status -eq 1 ]]
+    [[ $output == '[{"check":"collision","key":"<dry-run>","message":"--dry-run and <dry-run> are both mangled to '\''dry_run'\''"}]' ]]
+}
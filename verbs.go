@@ -0,0 +1,152 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// verbs.go: docopts sub-commands, see API_proposal.md
+//
+package main
+
+import (
+	"fmt"
+	"github.com/docopt/docopt-go"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A Verb is a docopts sub-command: docopts <verb> [options] ...
+// Verbs register themselves from an init() function with Register_verb().
+type Verb struct {
+	Name string
+	// one line description displayed in docopts's own help
+	Summary string
+	// the verb's docopt usage, patterns start with: docopts <verb>
+	Usage string
+	// argv doesn't contain the verb name
+	Run func(v *Verb, argv []string)
+}
+
+var verbs = make(map[string]*Verb)
+
+func Register_verb(v *Verb) {
+	if _, seen := verbs[v.Name]; seen {
+		panic(fmt.Sprintf("Register_verb(): verb already registered: '%s'", v.Name))
+	}
+	verbs[v.Name] = v
+}
+
+func Verb_names() []string {
+	names := make([]string, 0, len(verbs))
+	for name := range verbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Verbs list appended to docopts's own help.
+func Verbs_help() string {
+	help := "\nVerbs:\n"
+	for _, name := range Verb_names() {
+		help += fmt.Sprintf("  %-12s  %s\n", name, verbs[name].Summary)
+	}
+	help += "\nSee: docopts <verb> --help\n"
+	return help
+}
+
+// Parses docopts's own arguments for the verb. It exits on parse error or
+// if the verb's help is requested.
+func (v *Verb) Parse(argv []string) docopt.Opts {
+	// docopt uses the first word of the usage as the program name, so the verb is
+	// removed from the patterns and it is not part of argv either. This way
+	// OptionsFirst still allows options before the first positional argument.
+	doc := strings.Replace(v.Usage, "docopts "+v.Name, "docopts", -1)
+	parser := &docopt.Parser{
+		OptionsFirst:  true,
+		SkipHelpFlags: false,
+		HelpHandler:   v.HelpHandler,
+	}
+	arguments, err := parser.ParseArgs(doc, argv, "")
+	if err != nil {
+		docopts_error(fmt.Sprintf("%s:%%v", v.Name), err)
+	}
+	return arguments
+}
+
+// Usage of the verbs sharing docopts_parse() options, description and options
+// are added.
+func Parse_verb_usage(name, description, options string) string {
+	return fmt.Sprintf(`%[2]s
+Usage:
+  docopts %[1]s [options] USAGE : [<argv>...]
+  docopts %[1]s [options] [--no-declare] -A <name> USAGE : [<argv>...]
+  docopts %[1]s [options] -G <prefix> USAGE : [<argv>...]
+  docopts %[1]s [options] --no-mangle USAGE : [<argv>...]
+  docopts %[1]s [options] --json USAGE : [<argv>...]
+
+Arguments:
+  USAGE                         The help message in docopt format.
+                                If - is given, read it from standard input.
+
+Options:
+  -h, --help                    Show this help.
+  -V <msg>, --version=<msg>     A version message.
+                                If - is given, read the version message from
+                                standard input.  If USAGE is also read from
+                                standard input, it is read first.
+  -s <str>, --separator=<str>   The string to use to separate USAGE from the
+                                version message when both are given via
+                                standard input. [default: ----]
+` + Output_options + `%[3]s`, name, description, options)
+}
+
+// Regular help handler for verbs: -h and --help are not polymorphic.
+func (v *Verb) HelpHandler(err error, usage string) {
+	if err != nil {
+		msg := err.Error()
+		if msg == "" {
+			// docopt gives no detail when no pattern matched
+			msg = "invalid arguments"
+		}
+		fmt.Fprintf(os.Stderr, "docopts %s:error: %s\n%s\n", v.Name, msg, v.Short_usage())
+		os.Exit(1)
+	} else {
+		fmt.Println(strings.TrimSpace(v.Usage))
+		os.Exit(0)
+	}
+}
+
+// The Usage: bloc only of the verb's usage.
+func (v *Verb) Short_usage() string {
+	start := strings.Index(v.Usage, "Usage:")
+	if start < 0 {
+		return strings.TrimSpace(v.Usage)
+	}
+	usage := v.Usage[start:]
+	if end := strings.Index(usage, "\n\n"); end >= 0 {
+		usage = usage[:end]
+	}
+	return usage
+}
+
+func init() {
+	Register_verb(&Verb{
+		Name:    "compat",
+		Summary: "Legacy -h <msg> command line, same as without verb.",
+		Usage:   Usage,
+		Run: func(v *Verb, argv []string) {
+			docopts_compat(argv)
+		},
+	})
+
+	Register_verb(&Verb{
+		Name:    "parse",
+		Summary: "Parse <argv> according to USAGE and output the result.",
+		Usage: Parse_verb_usage("parse", "Parse <argv> according to USAGE and output the result.\n",
+			`  --debug                       Output extra parsing information for debugging
+                                on standard error, see: docopts debug --help
+`),
+		Run: func(v *Verb, argv []string) {
+			arguments := v.Parse(argv)
+			docopts_parse(arguments, arguments["USAGE"].(string))
+		},
+	})
+}
@@ -0,0 +1,107 @@
+// vim: set ts=4 sw=4 sts=4 noet:
+//
+// unit test for verbs.go
+//
+package main
+
+import (
+	"github.com/docopt/docopts/pkg/grammar"
+	"reflect"
+	"testing"
+)
+
+func TestVerb_names(t *testing.T) {
+	names := Verb_names()
+	for _, expect := range []string{"compat", "parse"} {
+		found := false
+		for _, n := range names {
+			if n == expect {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Verb_names() verb '%s' not registered, got: %v", expect, names)
+		}
+	}
+}
+
+func TestVerb_Parse(t *testing.T) {
+	tables := []struct {
+		argv   []string
+		expect map[string]interface{}
+	}{
+		{
+			[]string{"-A", "args", "Usage: prog [-v]", ":", "-v"},
+			map[string]interface{}{
+				"-A":     "args",
+				"USAGE":  "Usage: prog [-v]",
+				"<argv>": []string{"-v"},
+			},
+		},
+		{
+			// options after : belong to the parsed program
+			[]string{"Usage: prog [--help]", ":", "--help", "-G", "x"},
+			map[string]interface{}{
+				"-A":     nil,
+				"-G":     nil,
+				"--help": false,
+				"USAGE":  "Usage: prog [--help]",
+				"<argv>": []string{"--help", "-G", "x"},
+			},
+		},
+		{
+			[]string{"--json", "-", ":"},
+			map[string]interface{}{
+				"--json": true,
+				"USAGE":  "-",
+				"<argv>": []string{},
+			},
+		},
+	}
+
+	v := verbs["parse"]
+	for _, table := range tables {
+		arguments := v.Parse(table.argv)
+		for k, expect := range table.expect {
+			if !reflect.DeepEqual(arguments[k], expect) {
+				t.Errorf("Verb.Parse for %v key '%s'\ngot: '%v'\nwant: '%v'\n", table.argv, k, arguments[k], expect)
+			}
+		}
+	}
+}
+
+func TestVerb_Short_usage(t *testing.T) {
+	v := &Verb{
+		Name:  "dummy",
+		Usage: "Some text.\n\nUsage:\n  docopts dummy FILE\n\nOptions:\n  -h  help\n",
+	}
+	expect := "Usage:\n  docopts dummy FILE"
+	if res := v.Short_usage(); res != expect {
+		t.Errorf("Short_usage()\ngot: '%v'\nwant: '%v'\n", res, expect)
+	}
+}
+
+func TestParse_verb_usage(t *testing.T) {
+	verb_options := make(map[string]*grammar.Option)
+	for _, o := range grammar.Parse_options(verbs["parse"].Usage) {
+		verb_options[o.Name()] = o
+	}
+	shared := make(map[string]bool)
+	for _, o := range grammar.Parse_options("Options:\n" + Output_options) {
+		shared[o.Name()] = true
+	}
+	// the parse verb has the options of docopts, but its own -h and --debug
+	for _, o := range grammar.Parse_options(Usage) {
+		if o.Name() == "--help" || o.Name() == "--debug" {
+			continue
+		}
+		found := verb_options[o.Name()]
+		if found == nil || found.Argcount != o.Argcount || found.Default != o.Default ||
+			shared[o.Name()] && found.Description != o.Description {
+			t.Errorf("Parse_verb_usage option %s\ngot: %+v\nwant: %+v", o.Name(), found, o)
+		}
+	}
+	if !shared["--options-first"] || !shared["--config-option"] {
+		t.Errorf("Output_options got: %v", shared)
+	}
+}